@@ -28,6 +28,8 @@ type DeribitRestClient struct {
 	Client      *http.Client
 	ClientID    string
 	SecretKey   string
+	AuthMethod  deribit.AuthMethod
+	Scope       models.Scopes
 	BaseURL     string
 	AccessToken *string
 	Logger      *logrus.Logger
@@ -43,6 +45,8 @@ func NewDeribitRestClient(cfg *deribit.Configuration) *DeribitRestClient {
 		Client:      hc,
 		ClientID:    cfg.Credential.ApiKey,
 		SecretKey:   cfg.Credential.SecretKey,
+		AuthMethod:  cfg.Credential.AuthMethod,
+		Scope:       cfg.Credential.Scope,
 		BaseURL:     cfg.BaseUrl,
 		AccessToken: nil,
 		Logger:      cfg.Logger,
@@ -53,10 +57,21 @@ func (d *DeribitRestClient) GetAuthToken() (string, error) {
 	authURL := d.BaseURL + "/public/auth"
 	d.Logger.Debugf("Final auth URL: %s", authURL)
 
-	params := url.Values{}
-	params.Set("grant_type", "client_credentials")
-	params.Set("client_id", d.ClientID)
-	params.Set("client_secret", d.SecretKey)
+	credential := deribit.Credential{
+		ApiKey:     d.ClientID,
+		SecretKey:  d.SecretKey,
+		AuthMethod: d.AuthMethod,
+		Scope:      d.Scope,
+	}
+	authParams, err := credential.AuthParams()
+	if err != nil {
+		return "", fmt.Errorf("failed to build auth params: %w", err)
+	}
+
+	params, err := queryValues(authParams)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode auth params: %w", err)
+	}
 
 	fullURL := authURL + "?" + params.Encode()
 	d.Logger.Debugf("Full URL with params: %s", fullURL)
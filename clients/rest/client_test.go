@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -63,6 +64,42 @@ func TestGetAuthToken(t *testing.T) {
 	assert.Equal(t, "test-token", token)
 }
 
+func TestGetAuthToken_ClientSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "client_signature", q.Get("grant_type"))
+		assert.Equal(t, "test-key", q.Get("client_id"))
+		assert.Empty(t, q.Get("client_secret"))
+
+		timestamp, err := strconv.ParseInt(q.Get("timestamp"), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, deribit.ClientSignature("test-secret", timestamp, q.Get("nonce"), q.Get("data")), q.Get("signature"))
+
+		response := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result": map[string]interface{}{
+				"access_token": "test-token",
+			},
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := &DeribitRestClient{
+		Client:     http.DefaultClient,
+		ClientID:   "test-key",
+		SecretKey:  "test-secret",
+		AuthMethod: deribit.AuthMethodClientSignature,
+		BaseURL:    server.URL,
+		Logger:     logrus.New(),
+	}
+
+	token, err := client.GetAuthToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "test-token", token)
+}
+
 func TestGetOrderbook(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/public/get_order_book", r.URL.Path)
@@ -1,9 +1,35 @@
 package rest
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
 	"github.com/shopspring/decimal"
 )
 
 func roundToTickSize(price decimal.Decimal, tickSize decimal.Decimal) decimal.Decimal {
 	return price.Div(tickSize).Round(0).Mul(tickSize)
 }
+
+// queryValues encodes the JSON fields of params as url query values
+func queryValues(params interface{}) (url.Values, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	for key, value := range fields {
+		values.Set(key, fmt.Sprintf("%v", value))
+	}
+	return values, nil
+}
@@ -1,33 +1,177 @@
 package websocket
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
 	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/sourcegraph/jsonrpc2"
 )
 
-func (c *DeribitWSClient) Auth(apiKey string, secretKey string) (err error) {
-	params := models.ClientCredentialsParams{
-		GrantType:    "client_credentials",
-		ClientID:     apiKey,
-		ClientSecret: secretKey,
+func (c *DeribitWSClient) Auth(apiKey string, secretKey string, scope ...models.Scope) (err error) {
+	return c.AuthContext(c.defaultContext(), apiKey, secretKey, scope...)
+}
+
+// AuthContext authenticates the connection and then subscribes the private
+// channels queued while unauthenticated. The token is requested with scope,
+// or with the scope of the configured credential when none is given.
+// Reconnects no longer restore the subaccount or named session of earlier
+// ExchangeToken and ForkToken calls.
+func (c *DeribitWSClient) AuthContext(ctx context.Context, apiKey string, secretKey string, scope ...models.Scope) (err error) {
+	if len(scope) == 0 {
+		scope = c.credential.Scope
 	}
-	var result models.AuthResponse
-	err = c.Call("public/auth", params, &result)
+
+	if err = c.auth(ctx, apiKey, secretKey, scope); err != nil {
+		return
+	}
+	c.setSwitches(nil)
+
+	c.subscribe(ctx)
+	return
+}
+
+// auth authenticates with the AuthMethod of the configured credential
+func (c *DeribitWSClient) auth(ctx context.Context, apiKey string, secretKey string, scope models.Scopes) (err error) {
+	credential := deribit.Credential{
+		ApiKey:     apiKey,
+		SecretKey:  secretKey,
+		AuthMethod: c.credential.AuthMethod,
+		Scope:      scope,
+	}
+	params, err := credential.AuthParams()
 	if err != nil {
 		return
 	}
+	return c.authWith(ctx, params)
+}
 
-	c.authentication = &websocketmodels.Authentication{
+// authWith calls public/auth with params of any grant type and stores the
+// granted tokens
+func (c *DeribitWSClient) authWith(ctx context.Context, params interface{}) (err error) {
+	_, err = c.authCall(ctx, "public/auth", params)
+	return
+}
+
+// authCall calls a method returning an AuthResponse and replaces the stored
+// tokens with the result
+func (c *DeribitWSClient) authCall(ctx context.Context, method string, params interface{}) (result models.AuthResponse, err error) {
+	err = c.CallContext(ctx, method, params, &result)
+	if err != nil {
+		return
+	}
+
+	expiresIn := time.Duration(result.ExpiresIn) * time.Second
+	c.setAuthentication(&websocketmodels.Authentication{
 		AccessToken:  result.AccessToken,
 		RefreshToken: result.RefreshToken,
+		ExpiresIn:    expiresIn,
+		ExpiresAt:    time.Now().Add(expiresIn),
+		Scope:        result.Scope,
+	})
+	c.loggedOut.Store(false)
+
+	return
+}
+
+func (c *DeribitWSClient) Logout(params *models.LogoutParams) (err error) {
+	return c.LogoutContext(c.defaultContext(), params)
+}
+
+// LogoutContext ends the authenticated session, with InvalidateToken the
+// tokens of the session are revoked by the server. The server closes the
+// connection, which is re-established without authentication until Auth is
+// called again.
+func (c *DeribitWSClient) LogoutContext(ctx context.Context, params *models.LogoutParams) (err error) {
+	if params == nil {
+		params = &models.LogoutParams{}
 	}
 
+	err = c.CallContext(ctx, "private/logout", params, nil)
+	// private/logout has no response, the server closes the connection
+	if errors.Is(err, jsonrpc2.ErrClosed) {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	c.loggedOut.Store(true)
+	c.setAuthentication(nil)
+	c.setSwitches(nil)
+	c.emitConnectionEvent(EventLoggedOut, websocketmodels.ConnectionEvent{})
 	return
 }
 
-func (c *DeribitWSClient) Logout() (err error) {
-	var result = struct {
-	}{}
-	err = c.Call("public/auth", nil, &result)
+func (c *DeribitWSClient) ExchangeToken(params *models.ExchangeTokenParams) (result models.AuthResponse, err error) {
+	return c.ExchangeTokenContext(c.defaultContext(), params)
+}
+
+// ExchangeTokenContext switches the session to the subaccount params.SubjectID,
+// the refresh token of the current session is used when none is given. The
+// private channels queued while unauthenticated are subscribed afterwards.
+// The switch is applied again when the configured credentials log in after a
+// reconnect.
+func (c *DeribitWSClient) ExchangeTokenContext(ctx context.Context, params *models.ExchangeTokenParams) (result models.AuthResponse, err error) {
+	p := *params
+	if p.RefreshToken == "" {
+		if p.RefreshToken, err = c.currentRefreshToken(); err != nil {
+			return
+		}
+	}
+
+	if result, err = c.authCall(ctx, "public/exchange_token", p); err != nil {
+		return
+	}
+	c.addSwitch(sessionSwitch{method: "public/exchange_token", params: func(refreshToken string) interface{} {
+		p := p
+		p.RefreshToken = refreshToken
+		return p
+	}})
+	c.emitConnectionEvent(EventAuthenticated, websocketmodels.ConnectionEvent{})
+
+	c.subscribe(ctx)
 	return
 }
+
+func (c *DeribitWSClient) ForkToken(params *models.ForkTokenParams) (result models.AuthResponse, err error) {
+	return c.ForkTokenContext(c.defaultContext(), params)
+}
+
+// ForkTokenContext creates the named session params.SessionName from the
+// current session, the refresh token of the current session is used when
+// none is given. The private channels queued while unauthenticated are
+// subscribed afterwards. The session is forked again when the configured
+// credentials log in after a reconnect.
+func (c *DeribitWSClient) ForkTokenContext(ctx context.Context, params *models.ForkTokenParams) (result models.AuthResponse, err error) {
+	p := *params
+	if p.RefreshToken == "" {
+		if p.RefreshToken, err = c.currentRefreshToken(); err != nil {
+			return
+		}
+	}
+
+	if result, err = c.authCall(ctx, "public/fork_token", p); err != nil {
+		return
+	}
+	c.addSwitch(sessionSwitch{method: "public/fork_token", params: func(refreshToken string) interface{} {
+		p := p
+		p.RefreshToken = refreshToken
+		return p
+	}})
+	c.emitConnectionEvent(EventAuthenticated, websocketmodels.ConnectionEvent{})
+
+	c.subscribe(ctx)
+	return
+}
+
+func (c *DeribitWSClient) currentRefreshToken() (string, error) {
+	auth := c.getAuthentication()
+	if auth == nil || auth.RefreshToken == "" {
+		return "", ErrUnAuthorized
+	}
+	return auth.RefreshToken, nil
+}
@@ -0,0 +1,280 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_ClientSignature(t *testing.T) {
+	s := newMockServer(t)
+
+	var params models.ClientSignatureParams
+	var mu sync.Mutex
+	s.Handle("public/auth", func(raw json.RawMessage) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+		if params.Signature != deribit.ClientSignature("secret", params.Timestamp, params.Nonce, params.Data) {
+			return nil, &jsonrpc2.Error{Code: 13004, Message: "invalid_credentials"}
+		}
+		return map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"expires_in":    900,
+		}, nil
+	})
+
+	newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.Credential = deribit.Credential{ApiKey: "key", SecretKey: "secret", AuthMethod: deribit.AuthMethodClientSignature}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "client_signature", params.GrantType)
+	assert.Equal(t, "key", params.ClientID)
+	assert.NotEmpty(t, params.Nonce)
+}
+
+func TestLogout(t *testing.T) {
+	s := newMockServer(t)
+	auth := &authRecorder{expiresIn: 900}
+	s.Handle("public/auth", auth.handle)
+
+	client := newMockClient(t, s, fastReconnect, withCredential)
+	var rec eventRecorder
+	rec.listen(client, EventLoggedOut)
+
+	require.NoError(t, client.LogoutContext(context.Background(), &models.LogoutParams{InvalidateToken: true}))
+
+	assert.False(t, client.isAuthenticated())
+	assert.JSONEq(t, `{"invalidate_token":true}`, string(s.LastParams("private/logout")))
+	assert.Eventually(t, func() bool {
+		return rec.has(EventLoggedOut)
+	}, time.Second, 10*time.Millisecond)
+
+	// the connection is re-established without authentication
+	require.Eventually(t, func() bool {
+		return s.Accepted() == 2 && client.IsConnected()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"client_credentials"}, auth.Grants())
+	assert.False(t, client.isAuthenticated())
+
+	_, err := client.GetPositionsContext(context.Background(), &models.GetPositionsParams{Currency: "BTC"})
+	assert.Error(t, err)
+
+	require.NoError(t, client.Auth("key", "secret"))
+	assert.True(t, client.isAuthenticated())
+}
+
+func TestExchangeToken(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/exchange_token", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"access_token":  "subaccount-access-token",
+			"refresh_token": "subaccount-refresh-token",
+			"expires_in":    900,
+			"scope":         "session:sub trade:read_write",
+		}, nil
+	})
+
+	client := newMockClient(t, s, withCredential)
+
+	result, err := client.ExchangeTokenContext(context.Background(), &models.ExchangeTokenParams{SubjectID: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "subaccount-access-token", result.AccessToken)
+	assert.JSONEq(t, `{"refresh_token":"refresh-token","subject_id":10}`, string(s.LastParams("public/exchange_token")))
+
+	assert.Equal(t, "subaccount-access-token", client.accessToken())
+	assert.True(t, client.GrantedScopes().Allows(models.ScopeTradeReadWrite))
+}
+
+func TestForkToken(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/fork_token", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"access_token":  "forked-access-token",
+			"refresh_token": "forked-refresh-token",
+			"expires_in":    900,
+			"scope":         "session:worker",
+		}, nil
+	})
+
+	client := newMockClient(t, s)
+
+	_, err := client.ForkTokenContext(context.Background(), &models.ForkTokenParams{SessionName: "worker"})
+	assert.ErrorIs(t, err, ErrUnAuthorized)
+
+	require.NoError(t, client.Auth("key", "secret"))
+
+	_, err = client.ForkTokenContext(context.Background(), &models.ForkTokenParams{SessionName: "worker"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"refresh_token":"refresh-token","session_name":"worker"}`, string(s.LastParams("public/fork_token")))
+
+	session, ok := client.GrantedScopes().Session()
+	assert.True(t, ok)
+	assert.Equal(t, "worker", session)
+	assert.Equal(t, "forked-refresh-token", client.getAuthentication().RefreshToken)
+}
+
+// accountAuth answers the auth methods with access tokens naming the account
+// and session they belong to, e.g. "main", "sub-10" or "main:worker". The
+// refresh token of an access token is its name with a "-refresh" suffix.
+type accountAuth struct {
+	mu            sync.Mutex
+	rejectRefresh bool
+	calls         []string
+}
+
+func (a *accountAuth) install(s *mockServer) {
+	s.Handle("public/auth", func(raw json.RawMessage) (interface{}, error) {
+		var p struct {
+			GrantType    string `json:"grant_type"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+		if p.GrantType != "refresh_token" {
+			return a.grant("client_credentials", "main")
+		}
+
+		a.mu.Lock()
+		reject := a.rejectRefresh
+		a.mu.Unlock()
+		if reject {
+			return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeUnauthorized, Message: "invalid_token"}
+		}
+		return a.grant("refresh_token", strings.TrimSuffix(p.RefreshToken, "-refresh"))
+	})
+	s.Handle("public/exchange_token", func(raw json.RawMessage) (interface{}, error) {
+		var p models.ExchangeTokenParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+		return a.grant("exchange_token", fmt.Sprintf("sub-%d", p.SubjectID))
+	})
+	s.Handle("public/fork_token", func(raw json.RawMessage) (interface{}, error) {
+		var p models.ForkTokenParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+		return a.grant("fork_token", strings.TrimSuffix(p.RefreshToken, "-refresh")+":"+p.SessionName)
+	})
+}
+
+func (a *accountAuth) grant(call, token string) (interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, call)
+
+	return map[string]interface{}{
+		"access_token":  token,
+		"refresh_token": token + "-refresh",
+		"expires_in":    900,
+		"scope":         fullAccessScope,
+	}, nil
+}
+
+func (a *accountAuth) setRejectRefresh(reject bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rejectRefresh = reject
+}
+
+func (a *accountAuth) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// reconnectAs drops the connection and waits until the client is connected
+// with the access token account again
+func reconnectAs(t *testing.T, s *mockServer, client *DeribitWSClient, account string) {
+	t.Helper()
+
+	accepted := s.Accepted()
+	s.DropConnections()
+	require.Eventually(t, func() bool {
+		return s.Accepted() > accepted && client.IsConnected() && client.accessToken() == account
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestExchangeToken_Reconnect(t *testing.T) {
+	s := newMockServer(t)
+	auth := &accountAuth{}
+	auth.install(s)
+	client := newMockClient(t, s, fastReconnect, withCredential)
+
+	_, err := client.ExchangeTokenContext(context.Background(), &models.ExchangeTokenParams{SubjectID: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "sub-10", client.accessToken())
+
+	// the subaccount session is renewed with its refresh token
+	reconnectAs(t, s, client, "sub-10")
+	assert.Equal(t, []string{"client_credentials", "exchange_token", "refresh_token"}, auth.Calls())
+
+	// and exchanged again after a login with the credential
+	auth.setRejectRefresh(true)
+	reconnectAs(t, s, client, "sub-10")
+	assert.Equal(t, []string{"client_credentials", "exchange_token", "refresh_token", "client_credentials", "exchange_token"}, auth.Calls())
+	assert.JSONEq(t, `{"refresh_token":"main-refresh","subject_id":10}`, string(s.LastParams("public/exchange_token")))
+}
+
+func TestForkToken_Reconnect(t *testing.T) {
+	s := newMockServer(t)
+	auth := &accountAuth{}
+	auth.install(s)
+	client := newMockClient(t, s, fastReconnect, withCredential)
+
+	_, err := client.ForkTokenContext(context.Background(), &models.ForkTokenParams{SessionName: "worker"})
+	require.NoError(t, err)
+	assert.Equal(t, "main:worker", client.accessToken())
+
+	reconnectAs(t, s, client, "main:worker")
+	assert.Equal(t, []string{"client_credentials", "fork_token", "refresh_token"}, auth.Calls())
+
+	auth.setRejectRefresh(true)
+	reconnectAs(t, s, client, "main:worker")
+	assert.Equal(t, []string{"client_credentials", "fork_token", "refresh_token", "client_credentials", "fork_token"}, auth.Calls())
+
+	// a new login leaves the named session
+	require.NoError(t, client.Auth("key", "secret"))
+	reconnectAs(t, s, client, "main")
+}
+
+func TestExchangeToken_SubscribesPending(t *testing.T) {
+	s := newMockServer(t)
+	auth := &accountAuth{}
+	auth.install(s)
+	client := newMockClient(t, s)
+
+	// authenticated without subscribing the pending channels, like a
+	// session renewed by a reconnect before the channel was requested
+	client.setAuthentication(&websocketmodels.Authentication{RefreshToken: "main-refresh"})
+	const channel = "user.orders.BTC-PERPETUAL.raw"
+	_, err := client.Subscribe([]string{channel})
+	require.NoError(t, err)
+	assert.Empty(t, client.ActiveSubscriptions())
+
+	_, err = client.ExchangeTokenContext(context.Background(), &models.ExchangeTokenParams{SubjectID: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{channel}, client.ActiveSubscriptions())
+
+	_, err = client.ForkTokenContext(context.Background(), &models.ForkTokenParams{SessionName: "worker"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Calls("private/subscribe"))
+}
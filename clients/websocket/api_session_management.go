@@ -1,25 +1,43 @@
 package websocket
 
 import (
+	"context"
+
 	"github.com/BestNathan/deribit-api/pkg/models"
 )
 
 func (c *DeribitWSClient) SetHeartbeat(params *models.SetHeartbeatParams) (result string, err error) {
-	err = c.Call("public/set_heartbeat", params, &result)
+	return c.SetHeartbeatContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) SetHeartbeatContext(ctx context.Context, params *models.SetHeartbeatParams) (result string, err error) {
+	err = c.CallContext(ctx, "public/set_heartbeat", params, &result)
 	return
 }
 
 func (c *DeribitWSClient) DisableHeartbeat() (result string, err error) {
-	err = c.Call("public/disable_heartbeat", nil, &result)
+	return c.DisableHeartbeatContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) DisableHeartbeatContext(ctx context.Context) (result string, err error) {
+	err = c.CallContext(ctx, "public/disable_heartbeat", nil, &result)
 	return
 }
 
 func (c *DeribitWSClient) EnableCancelOnDisconnect() (result string, err error) {
-	err = c.Call("private/enable_cancel_on_disconnect", nil, &result)
+	return c.EnableCancelOnDisconnectContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) EnableCancelOnDisconnectContext(ctx context.Context) (result string, err error) {
+	err = c.CallContext(ctx, "private/enable_cancel_on_disconnect", nil, &result)
 	return
 }
 
 func (c *DeribitWSClient) DisableCancelOnDisconnect() (result string, err error) {
-	err = c.Call("private/disable_cancel_on_disconnect", nil, &result)
+	return c.DisableCancelOnDisconnectContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) DisableCancelOnDisconnectContext(ctx context.Context) (result string, err error) {
+	err = c.CallContext(ctx, "private/disable_cancel_on_disconnect", nil, &result)
 	return
 }
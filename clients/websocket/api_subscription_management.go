@@ -1,25 +1,61 @@
 package websocket
 
 import (
+	"context"
+
 	"github.com/BestNathan/deribit-api/pkg/models"
 )
 
 func (c *DeribitWSClient) PublicSubscribe(params *models.SubscribeParams) (result models.SubscribeResponse, err error) {
-	err = c.Call("public/subscribe", params, &result)
+	return c.PublicSubscribeContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) PublicSubscribeContext(ctx context.Context, params *models.SubscribeParams) (result models.SubscribeResponse, err error) {
+	err = c.CallContext(ctx, "public/subscribe", params, &result)
 	return
 }
 
 func (c *DeribitWSClient) PublicUnsubscribe(params *models.UnsubscribeParams) (result models.UnsubscribeResponse, err error) {
-	err = c.Call("public/unsubscribe", params, &result)
+	return c.PublicUnsubscribeContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) PublicUnsubscribeContext(ctx context.Context, params *models.UnsubscribeParams) (result models.UnsubscribeResponse, err error) {
+	err = c.CallContext(ctx, "public/unsubscribe", params, &result)
 	return
 }
 
 func (c *DeribitWSClient) PrivateSubscribe(params *models.SubscribeParams) (result models.SubscribeResponse, err error) {
-	err = c.Call("private/subscribe", params, &result)
+	return c.PrivateSubscribeContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) PrivateSubscribeContext(ctx context.Context, params *models.SubscribeParams) (result models.SubscribeResponse, err error) {
+	err = c.CallContext(ctx, "private/subscribe", params, &result)
 	return
 }
 
 func (c *DeribitWSClient) PrivateUnsubscribe(params *models.UnsubscribeParams) (result models.UnsubscribeResponse, err error) {
-	err = c.Call("private/unsubscribe", params, &result)
+	return c.PrivateUnsubscribeContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) PrivateUnsubscribeContext(ctx context.Context, params *models.UnsubscribeParams) (result models.UnsubscribeResponse, err error) {
+	err = c.CallContext(ctx, "private/unsubscribe", params, &result)
+	return
+}
+
+func (c *DeribitWSClient) PublicUnsubscribeAll() (result string, err error) {
+	return c.PublicUnsubscribeAllContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) PublicUnsubscribeAllContext(ctx context.Context) (result string, err error) {
+	err = c.CallContext(ctx, "public/unsubscribe_all", nil, &result)
+	return
+}
+
+func (c *DeribitWSClient) PrivateUnsubscribeAll() (result string, err error) {
+	return c.PrivateUnsubscribeAllContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) PrivateUnsubscribeAllContext(ctx context.Context) (result string, err error) {
+	err = c.CallContext(ctx, "private/unsubscribe_all", nil, &result)
 	return
 }
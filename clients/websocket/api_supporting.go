@@ -1,20 +1,34 @@
 package websocket
 
 import (
+	"context"
+
 	"github.com/BestNathan/deribit-api/pkg/models"
 )
 
 func (c *DeribitWSClient) GetTime() (result int64, err error) {
-	err = c.Call("public/get_time", nil, &result)
+	return c.GetTimeContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) GetTimeContext(ctx context.Context) (result int64, err error) {
+	err = c.CallContext(ctx, "public/get_time", nil, &result)
 	return
 }
 
 func (c *DeribitWSClient) Hello(params *models.HelloParams) (result models.HelloResponse, err error) {
-	err = c.Call("public/hello", params, &result)
+	return c.HelloContext(c.defaultContext(), params)
+}
+
+func (c *DeribitWSClient) HelloContext(ctx context.Context, params *models.HelloParams) (result models.HelloResponse, err error) {
+	err = c.CallContext(ctx, "public/hello", params, &result)
 	return
 }
 
 func (c *DeribitWSClient) Test() (result models.TestResponse, err error) {
-	err = c.Call("public/test", nil, &result)
+	return c.TestContext(c.defaultContext())
+}
+
+func (c *DeribitWSClient) TestContext(ctx context.Context) (result models.TestResponse, err error) {
+	err = c.CallContext(ctx, "public/test", nil, &result)
 	return
 }
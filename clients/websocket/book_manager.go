@@ -0,0 +1,272 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/orderbook"
+)
+
+// ErrBookManagerClosed is returned by Add once the BookManager is closed
+var ErrBookManagerClosed = errors.New("websocket book manager closed")
+
+// BookManagerOptions configure NewBookManager
+type BookManagerOptions struct {
+	// Channel returns the book channel of an instrument, the raw channel by
+	// default. Use BookGroupChannel for grouped books.
+	Channel func(instrumentName string) Channel
+	// OrderBook configures the book of every instrument
+	OrderBook OrderBookOptions
+}
+
+// TopOfBook is the best bid and ask of an instrument, a side without orders
+// is the zero Level
+type TopOfBook struct {
+	InstrumentName string
+	Bid            orderbook.Level
+	Ask            orderbook.Level
+	ChangeID       int64
+	Timestamp      int64
+}
+
+// sameLevels tells whether t and other have the same best prices and amounts
+func (t TopOfBook) sameLevels(other TopOfBook) bool {
+	return t.Bid.Price.Equal(other.Bid.Price) && t.Bid.Amount.Equal(other.Bid.Amount) &&
+		t.Ask.Price.Equal(other.Ask.Price) && t.Ask.Amount.Equal(other.Ask.Amount)
+}
+
+// BookManager keeps the order books of a set of instruments and delivers on C
+// a TopOfBook whenever the best bid or ask of one of them changes in price or
+// amount. Delivery blocks the updates of the books while C is full. Errors of
+// the books are delivered on Err. C and Err are closed as well when the client
+// shuts down.
+type BookManager struct {
+	*stream[TopOfBook]
+
+	client Subscriber
+	opts   BookManagerOptions
+	// id is the id of the stream in the registry of the client
+	id uint64
+
+	mu     sync.Mutex
+	books  map[string]*OrderBook
+	tops   map[string]TopOfBook
+	closed bool
+}
+
+// NewBookManager returns a BookManager of the books of c, a DeribitWSClient
+// or a Pool
+func NewBookManager(c Subscriber, opts BookManagerOptions) *BookManager {
+	if opts.Channel == nil {
+		opts.Channel = func(instrumentName string) Channel {
+			return BookChannel(instrumentName, IntervalRaw)
+		}
+	}
+
+	m := &BookManager{
+		stream: newStream[TopOfBook](),
+		client: c,
+		opts:   opts,
+		books:  make(map[string]*OrderBook),
+		tops:   make(map[string]TopOfBook),
+	}
+	// a shutdown of the client closes the stream, so a delivery nobody reads
+	// does not block it
+	m.id = c.streamRegistry().addLocal(c, m.stream)
+	return m
+}
+
+// Add subscribes the books of instrumentNames in one subscription, instruments
+// already added are skipped. Instruments whose channel is not subscribed are
+// dropped again and reported in the returned error.
+func (m *BookManager) Add(ctx context.Context, instrumentNames ...string) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrBookManagerClosed
+	}
+
+	var errs []error
+	var channels []string
+	added := make(map[string]string)
+	for _, name := range instrumentNames {
+		if _, ok := m.books[name]; ok {
+			continue
+		}
+		channel := m.opts.Channel(name).String()
+		if _, ok := added[channel]; ok {
+			continue
+		}
+
+		book, err := newOrderBook(m.client, channel, name, m.opts.OrderBook, m.changed)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if !m.client.spawn(func() { m.forwardErrors(book) }) {
+			book.sub.release()
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrClientClosed))
+			continue
+		}
+		m.books[name] = book
+
+		channels = append(channels, channel)
+		added[channel] = name
+	}
+	m.mu.Unlock()
+
+	if len(channels) == 0 {
+		return errors.Join(errs...)
+	}
+
+	results, _ := m.client.subscribeStream(ctx, channels)
+	var failed []string
+	for _, result := range results {
+		if result.Status != websocketmodels.SubscriptionFailed && result.Status != websocketmodels.SubscriptionRejected {
+			continue
+		}
+		err := result.Err
+		if err == nil {
+			err = ErrSubscriptionRejected
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", added[result.Channel], err))
+		failed = append(failed, added[result.Channel])
+	}
+	if err := m.RemoveContext(ctx, failed...); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Remove is RemoveContext with the default context of the client
+func (m *BookManager) Remove(instrumentNames ...string) error {
+	return m.RemoveContext(m.client.defaultContext(), instrumentNames...)
+}
+
+// RemoveContext stops the books of instrumentNames and unsubscribes their
+// channels in one request
+func (m *BookManager) RemoveContext(ctx context.Context, instrumentNames ...string) error {
+	m.mu.Lock()
+	books := make([]*OrderBook, 0, len(instrumentNames))
+	for _, name := range instrumentNames {
+		if book, ok := m.books[name]; ok {
+			books = append(books, book)
+			delete(m.books, name)
+			delete(m.tops, name)
+		}
+	}
+	m.mu.Unlock()
+
+	return m.release(ctx, books)
+}
+
+// release closes books and unsubscribes the channels no longer used
+func (m *BookManager) release(ctx context.Context, books []*OrderBook) error {
+	var channels []string
+	for _, book := range books {
+		if book.sub.release() {
+			channels = append(channels, book.Channel())
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	_, err := m.client.UnsubscribeContext(ctx, channels)
+	return err
+}
+
+// Book returns the book of instrumentName
+func (m *BookManager) Book(instrumentName string) (*OrderBook, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[instrumentName]
+	return book, ok
+}
+
+// Top returns the last TopOfBook of instrumentName, false before the first
+// update of its book
+func (m *BookManager) Top(instrumentName string) (TopOfBook, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	top, ok := m.tops[instrumentName]
+	return top, ok
+}
+
+// Instruments returns the sorted names of the managed instruments
+func (m *BookManager) Instruments() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.books))
+	for name := range m.books {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close is CloseContext with the default context of the client
+func (m *BookManager) Close() error {
+	return m.CloseContext(m.client.defaultContext())
+}
+
+// CloseContext stops all books, unsubscribes their channels and closes C and
+// Err
+func (m *BookManager) CloseContext(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	books := make([]*OrderBook, 0, len(m.books))
+	for _, book := range m.books {
+		books = append(books, book)
+	}
+	m.books, m.tops = make(map[string]*OrderBook), make(map[string]TopOfBook)
+	m.mu.Unlock()
+
+	err := m.release(ctx, books)
+	m.client.streamRegistry().removeLocal(m.id)
+	m.closeLocal()
+	return err
+}
+
+// changed is the ChangeFunc of every book, delivering its TopOfBook when it
+// differs from the last one
+func (m *BookManager) changed(book *orderbook.Book, update *orderbook.Update) {
+	top := TopOfBook{
+		InstrumentName: book.Instrument(),
+		ChangeID:       update.ChangeID,
+		Timestamp:      update.Timestamp,
+	}
+	top.Bid, _ = book.BestBid()
+	top.Ask, _ = book.BestAsk()
+
+	m.mu.Lock()
+	if _, ok := m.books[top.InstrumentName]; !ok {
+		m.mu.Unlock()
+		return
+	}
+	if last, ok := m.tops[top.InstrumentName]; ok && last.sameLevels(top) {
+		m.mu.Unlock()
+		return
+	}
+	m.tops[top.InstrumentName] = top
+	m.mu.Unlock()
+
+	m.send(top)
+}
+
+func (m *BookManager) forwardErrors(book *OrderBook) {
+	for err := range book.Err() {
+		m.fail(err)
+	}
+}
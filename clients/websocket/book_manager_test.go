@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ethBookChannel = "book.ETH-PERPETUAL.raw"
+
+func instrumentBook(instrument string, prev, change int64, bids, asks []interface{}) map[string]interface{} {
+	data := rawBook(prev, change, bids, asks)
+	data["instrument_name"] = instrument
+	return data
+}
+
+// topString renders the levels of top as bid/ask price@amount
+func topString(top TopOfBook) string {
+	level := func(l orderbook.Level) string {
+		return l.Price.String() + "@" + l.Amount.String()
+	}
+	return top.InstrumentName + " " + level(top.Bid) + " " + level(top.Ask)
+}
+
+func TestBookManager_TopOfBook(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	m := NewBookManager(client, BookManagerOptions{})
+	defer m.Close()
+	require.NoError(t, m.Add(context.Background(), "BTC-PERPETUAL", "ETH-PERPETUAL", "BTC-PERPETUAL"))
+
+	assert.Equal(t, 1, s.Calls("public/subscribe"))
+	assert.JSONEq(t, `{"channels":["book.BTC-PERPETUAL.raw","book.ETH-PERPETUAL.raw"]}`, string(s.LastParams("public/subscribe")))
+	assert.Equal(t, []string{"BTC-PERPETUAL", "ETH-PERPETUAL"}, m.Instruments())
+
+	s.Publish(rawBookChannel, rawBook(0, 1,
+		[]interface{}{bookItem("new", 100, 10), bookItem("new", 99, 20)},
+		[]interface{}{bookItem("new", 101, 5)}))
+	assert.Equal(t, "BTC-PERPETUAL 100@10 101@5", topString(receive(t, m.C())))
+
+	// below the top
+	s.Publish(rawBookChannel, rawBook(1, 2, []interface{}{bookItem("change", 99, 25)}, nil))
+	// same levels at the top
+	s.Publish(rawBookChannel, rawBook(2, 3, []interface{}{bookItem("change", 100, 10)}, nil))
+	s.Publish(rawBookChannel, rawBook(3, 4, nil, []interface{}{bookItem("change", 101, 6)}))
+	top := receive(t, m.C())
+	assert.Equal(t, "BTC-PERPETUAL 100@10 101@6", topString(top))
+	assert.Equal(t, int64(4), top.ChangeID)
+
+	s.Publish(ethBookChannel, instrumentBook("ETH-PERPETUAL", 0, 1, []interface{}{bookItem("new", 2000, 1)}, nil))
+	assert.Equal(t, "ETH-PERPETUAL 2000@1 0@0", topString(receive(t, m.C())))
+
+	last, ok := m.Top("BTC-PERPETUAL")
+	require.True(t, ok)
+	assert.Equal(t, top, last)
+	book, ok := m.Book("BTC-PERPETUAL")
+	require.True(t, ok)
+	assert.Equal(t, 2, book.Len(orderbook.Bid))
+
+	require.NoError(t, m.Remove("ETH-PERPETUAL"))
+	assert.JSONEq(t, `{"channels":["book.ETH-PERPETUAL.raw"]}`, string(s.LastParams("public/unsubscribe")))
+	assert.Equal(t, []string{"BTC-PERPETUAL"}, m.Instruments())
+	_, ok = m.Top("ETH-PERPETUAL")
+	assert.False(t, ok)
+
+	require.NoError(t, m.Close())
+	assert.Empty(t, client.Subscriptions())
+	assert.ErrorIs(t, m.Add(context.Background(), "ETH-PERPETUAL"), ErrBookManagerClosed)
+	select {
+	case _, ok := <-m.C():
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("C not closed")
+	}
+}
+
+func TestBookManager_GroupChannel(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	m := NewBookManager(client, BookManagerOptions{Channel: func(instrumentName string) Channel {
+		return BookGroupChannel(instrumentName, "none", 1, "100ms")
+	}})
+	defer m.Close()
+	require.NoError(t, m.Add(context.Background(), "BTC-PERPETUAL"))
+	assert.Equal(t, []string{"book.BTC-PERPETUAL.none.1.100ms"}, client.Subscriptions())
+
+	group := func(change int64, bid, ask float64) map[string]interface{} {
+		return map[string]interface{}{
+			"instrument_name": "BTC-PERPETUAL",
+			"change_id":       change,
+			"bids":            [][]float64{{bid, 1}},
+			"asks":            [][]float64{{ask, 1}},
+		}
+	}
+	s.Publish("book.BTC-PERPETUAL.none.1.100ms", group(1, 100, 101))
+	s.Publish("book.BTC-PERPETUAL.none.1.100ms", group(2, 100, 101))
+	s.Publish("book.BTC-PERPETUAL.none.1.100ms", group(3, 100.5, 101))
+
+	assert.Equal(t, int64(1), receive(t, m.C()).ChangeID)
+	assert.Equal(t, "BTC-PERPETUAL 100.5@1 101@1", topString(receive(t, m.C())))
+}
+
+func TestBookManager_Rejected(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/subscribe", func(params json.RawMessage) (interface{}, error) {
+		return []string{rawBookChannel}, nil
+	})
+	client := newMockClient(t, s)
+
+	m := NewBookManager(client, BookManagerOptions{})
+	defer m.Close()
+
+	err := m.Add(context.Background(), "BTC-PERPETUAL", "ETH-PERPETUAL")
+	assert.ErrorIs(t, err, ErrSubscriptionRejected)
+	assert.ErrorContains(t, err, "ETH-PERPETUAL")
+	assert.Equal(t, []string{"BTC-PERPETUAL"}, m.Instruments())
+	assert.Equal(t, []string{rawBookChannel}, client.Subscriptions())
+}
+
+func TestBookManager_ClosedOnShutdown(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	m := NewBookManager(client, BookManagerOptions{})
+	require.NoError(t, m.Add(context.Background(), "BTC-PERPETUAL"))
+
+	// nobody reads C, the book blocks on the delivery once it is full
+	for i := 0; i < defaultStreamBuffer+2; i++ {
+		s.Publish(rawBookChannel, rawBook(int64(i), int64(i+1), []interface{}{bookItem("new", float64(i+1), 1)}, nil))
+	}
+	require.Eventually(t, func() bool { return len(m.C()) == defaultStreamBuffer }, 2*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, client.Close(ctx))
+
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("book manager not closed")
+	}
+	assert.NoError(t, m.Close())
+}
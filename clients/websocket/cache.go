@@ -0,0 +1,268 @@
+package websocket
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CachedNotification is the latest notification of a channel
+type CachedNotification struct {
+	Channel      string
+	Notification interface{}
+	// Received is when the notification was read from the connection
+	Received time.Time
+}
+
+// Age returns the time since the notification was received
+func (n CachedNotification) Age() time.Duration {
+	return time.Since(n.Received)
+}
+
+// EnableCache keeps the latest notification of the channels matched by
+// matchers, of every channel when none is given. Cached notifications are
+// returned by Latest and replayed to listeners and typed streams added later.
+func (c *DeribitWSClient) EnableCache(matchers ...ChannelMatcher) {
+	c.cache.enable(matchers)
+}
+
+// Latest returns the latest notification of channel if it is cached
+func (c *DeribitWSClient) Latest(channel string) (CachedNotification, bool) {
+	return c.cache.latest(channel)
+}
+
+// LatestAs returns the latest notification of channel as T, it is false if
+// nothing is cached or the notification is neither a T nor a *T
+func LatestAs[T any](c Subscriber, channel string) (T, time.Time, bool) {
+	cached, ok := c.notificationCache().latest(channel)
+	if !ok {
+		var zero T
+		return zero, time.Time{}, false
+	}
+
+	value, err := convertNotification[T](channel, cached.Notification)
+	if err != nil {
+		return value, time.Time{}, false
+	}
+	return value, cached.Received, true
+}
+
+// notificationCache holds the latest notification per channel. Its entries
+// are locked only to store or read the value, never while listeners run, so
+// listeners may call Latest, On or Subscribe for their own channel.
+type notificationCache struct {
+	mu       sync.RWMutex
+	enabled  bool
+	matchers []ChannelMatcher
+	entries  map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	mu    sync.Mutex
+	value CachedNotification
+}
+
+func newNotificationCache() *notificationCache {
+	return &notificationCache{entries: make(map[string]*cacheEntry)}
+}
+
+func (r *notificationCache) enable(matchers []ChannelMatcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(matchers) == 0 {
+		r.matchers = nil
+	} else if !r.enabled || len(r.matchers) > 0 {
+		// caching every channel already covers the new matchers
+		r.matchers = append(r.matchers, matchers...)
+	}
+	r.enabled = true
+}
+
+// store caches the notification of channel if the channel is cached and
+// runs locked, if set, under the lock of its entry. Dispatches take the
+// Subscriptions of the channel in locked, see withStreamReplay.
+func (r *notificationCache) store(channel string, notification interface{}, received time.Time, locked func()) {
+	entry := r.entry(channel)
+	if entry == nil {
+		if locked != nil {
+			locked()
+		}
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.value = CachedNotification{Channel: channel, Notification: notification, Received: received}
+	if locked != nil {
+		locked()
+	}
+}
+
+// entry returns the entry of channel, created if the channel is cached, nil
+// if it is not
+func (r *notificationCache) entry(channel string) *cacheEntry {
+	r.mu.RLock()
+	entry, ok := r.entries[channel]
+	enabled := r.enabled
+	r.mu.RUnlock()
+	if ok {
+		return entry
+	}
+	if !enabled || !r.caches(channel) {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok = r.entries[channel]; !ok {
+		entry = &cacheEntry{}
+		r.entries[channel] = entry
+	}
+	return entry
+}
+
+func (r *notificationCache) caches(channel string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.matchers) == 0 {
+		return true
+	}
+	for _, m := range r.matchers {
+		if m.Match(channel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *notificationCache) latest(channel string) (CachedNotification, bool) {
+	r.mu.RLock()
+	entry, ok := r.entries[channel]
+	r.mu.RUnlock()
+	if !ok {
+		return CachedNotification{}, false
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	return entry.value, entry.value.Notification != nil
+}
+
+// forget drops the cached notifications of channels, used after unsubscribing
+func (r *notificationCache) forget(channels []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, channel := range channels {
+		delete(r.entries, channel)
+	}
+}
+
+// withReplay runs register and passes the cached notifications of the
+// channels selected by key, a channel name or a ChannelMatcher, to replay.
+// replay runs without any lock held, a notification dispatched meanwhile may
+// be passed to the new listener as well.
+func (r *notificationCache) withReplay(key interface{}, register func(), replay func(channel string, notification interface{})) {
+	switch key := key.(type) {
+	case string:
+		r.mu.RLock()
+		entry, ok := r.entries[key]
+		r.mu.RUnlock()
+		if !ok {
+			register()
+			return
+		}
+
+		entry.mu.Lock()
+		register()
+		value := entry.value
+		entry.mu.Unlock()
+
+		if value.Notification != nil {
+			replay(key, value.Notification)
+		}
+
+	case ChannelMatcher:
+		register()
+
+		r.mu.RLock()
+		var entries []*cacheEntry
+		for channel, entry := range r.entries {
+			if key.Match(channel) {
+				entries = append(entries, entry)
+			}
+		}
+		r.mu.RUnlock()
+
+		for _, entry := range entries {
+			entry.mu.Lock()
+			value := entry.value
+			entry.mu.Unlock()
+
+			if value.Notification != nil {
+				replay(value.Channel, value.Notification)
+			}
+		}
+
+	default:
+		register()
+	}
+}
+
+// withStreamReplay runs register and replays the cached notification of
+// channel under the lock of its entry. Dispatches take the Subscriptions of a
+// channel under the same lock, so a Subscription registered here gets every
+// notification once and in order. replay must not block, it sends to the
+// empty buffer of a new Subscription.
+func (r *notificationCache) withStreamReplay(channel string, register func(), replay func(channel string, notification interface{})) {
+	r.mu.RLock()
+	entry, ok := r.entries[channel]
+	r.mu.RUnlock()
+	if !ok {
+		register()
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	register()
+	if entry.value.Notification != nil {
+		replay(channel, entry.value.Notification)
+	}
+}
+
+// replayListener calls an emitter listener with a cached notification, the
+// channel is passed first to the listeners of a ChannelMatcher. Listeners
+// that do not take the notification are skipped.
+func replayListener(event interface{}, listener interface{}) func(channel string, notification interface{}) {
+	return func(channel string, notification interface{}) {
+		args := []reflect.Value{reflect.ValueOf(notification)}
+		if _, ok := event.(ChannelMatcher); ok {
+			args = []reflect.Value{reflect.ValueOf(channel), args[0]}
+		}
+
+		fn := reflect.ValueOf(listener)
+		if !callable(fn, args) {
+			return
+		}
+		fn.Call(args)
+	}
+}
+
+// callable tells whether fn is a function taking exactly args
+func callable(fn reflect.Value, args []reflect.Value) bool {
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return false
+	}
+	t := fn.Type()
+	if t.IsVariadic() || t.NumIn() != len(args) {
+		return false
+	}
+	for i, arg := range args {
+		if !arg.IsValid() || !arg.Type().AssignableTo(t.In(i)) {
+			return false
+		}
+	}
+	return true
+}
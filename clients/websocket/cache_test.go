@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tradesChannel = "trades.BTC-PERPETUAL.100ms"
+
+// cachedClient returns a client caching tickers, with tickerChannel cached at
+// timestamp 1
+func cachedClient(t *testing.T) (*mockServer, *DeribitWSClient) {
+	t.Helper()
+
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	client.EnableCache(ChannelKindTicker)
+
+	_, err := client.Subscribe([]string{tickerChannel, tradesChannel})
+	require.NoError(t, err)
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+	s.Publish(tradesChannel, []interface{}{})
+	require.Eventually(t, func() bool {
+		_, ok := client.Latest(tickerChannel)
+		return ok && client.DeliveryStats(tradesChannel).Delivered == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	return s, client
+}
+
+func TestCache_Latest(t *testing.T) {
+	_, client := cachedClient(t)
+
+	cached, ok := client.Latest(tickerChannel)
+	require.True(t, ok)
+	assert.Equal(t, tickerChannel, cached.Channel)
+	assert.IsType(t, &models.TickerNotification{}, cached.Notification)
+	assert.WithinDuration(t, time.Now(), cached.Received, time.Second)
+	assert.GreaterOrEqual(t, cached.Age(), time.Duration(0))
+
+	ticker, received, ok := LatestAs[models.TickerNotification](client, tickerChannel)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), ticker.Timestamp)
+	assert.Equal(t, cached.Received, received)
+
+	_, _, ok = LatestAs[*models.TradesNotification](client, tickerChannel)
+	assert.False(t, ok)
+
+	// only tickers are cached
+	_, ok = client.Latest(tradesChannel)
+	assert.False(t, ok)
+}
+
+func TestCache_Disabled(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	_, err := client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+	require.Eventually(t, func() bool {
+		return client.DeliveryStats(tickerChannel).Delivered == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, ok := client.Latest(tickerChannel)
+	assert.False(t, ok)
+}
+
+func TestCache_ReplayOn(t *testing.T) {
+	_, client := cachedClient(t)
+
+	var byChannel, byKind []int64
+	client.On(tickerChannel, func(n *models.TickerNotification) {
+		byChannel = append(byChannel, n.Timestamp)
+	})
+	client.On(ChannelKindTicker, func(channel string, n *models.TickerNotification) {
+		assert.Equal(t, tickerChannel, channel)
+		byKind = append(byKind, n.Timestamp)
+	})
+
+	// replayed before On returns
+	assert.Equal(t, []int64{1}, byChannel)
+	assert.Equal(t, []int64{1}, byKind)
+}
+
+func TestCache_ReplayStreams(t *testing.T) {
+	s, client := cachedClient(t)
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	l, err := Listen[*models.TickerNotification](client, ChannelPattern("ticker.*"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, int64(1), receive(t, sub.C()).Timestamp)
+	assert.Equal(t, int64(1), receive(t, l.C()).Data.Timestamp)
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 2})
+	assert.Equal(t, int64(2), receive(t, sub.C()).Timestamp)
+	assert.Equal(t, int64(2), receive(t, l.C()).Data.Timestamp)
+
+	ticker, _, ok := LatestAs[*models.TickerNotification](client, tickerChannel)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), ticker.Timestamp)
+}
+
+func TestCache_ForgetOnUnsubscribe(t *testing.T) {
+	_, client := cachedClient(t)
+
+	_, err := client.Unsubscribe([]string{tickerChannel})
+	require.NoError(t, err)
+
+	_, ok := client.Latest(tickerChannel)
+	assert.False(t, ok)
+}
+
+func TestCache_ListenerCallsBack(t *testing.T) {
+	s, client := cachedClient(t)
+
+	latest := make(chan int64, 1)
+	var once sync.Once
+	var sub *Subscription[*models.TickerNotification]
+	client.On(tickerChannel, func(n *models.TickerNotification) {
+		if n.Timestamp != 2 {
+			return
+		}
+		// the entry of the channel is not locked while listeners run
+		cached, ok := client.Latest(tickerChannel)
+		require.True(t, ok)
+		once.Do(func() {
+			client.On(tickerChannel, func(*models.TickerNotification) {})
+			var err error
+			sub, err = Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+			require.NoError(t, err)
+		})
+		latest <- cached.Notification.(*models.TickerNotification).Timestamp
+	})
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 2})
+	select {
+	case timestamp := <-latest:
+		assert.Equal(t, int64(2), timestamp)
+	case <-time.After(2 * time.Second):
+		t.Fatal("listener calling Latest deadlocked")
+	}
+	defer sub.Close()
+
+	// the Subscription added by the listener starts with the cached value
+	assert.Equal(t, int64(2), receive(t, sub.C()).Timestamp)
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 3})
+	assert.Equal(t, int64(3), receive(t, sub.C()).Timestamp)
+}
+
+func TestCache_ReplaySkipsMismatchedListener(t *testing.T) {
+	_, client := cachedClient(t)
+
+	assert.NotPanics(t, func() {
+		client.On(tickerChannel, func(n *models.TradesNotification) {})
+		client.On(ChannelKindTicker, func(n *models.TickerNotification) {})
+	})
+}
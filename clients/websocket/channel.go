@@ -0,0 +1,383 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidChannel = errors.New("websocket invalid channel")
+
+// ChannelKind identifies a Deribit subscription channel, its value is the
+// channel name prefix
+type ChannelKind string
+
+// Public channels
+const (
+	ChannelKindAnnouncements              ChannelKind = "announcements"
+	ChannelKindBook                       ChannelKind = "book"
+	ChannelKindChartTrades                ChannelKind = "chart.trades"
+	ChannelKindPriceIndex                 ChannelKind = "deribit_price_index"
+	ChannelKindPriceRanking               ChannelKind = "deribit_price_ranking"
+	ChannelKindPriceStatistics            ChannelKind = "deribit_price_statistics"
+	ChannelKindVolatilityIndex            ChannelKind = "deribit_volatility_index"
+	ChannelKindEstimatedExpirationPrice   ChannelKind = "estimated_expiration_price"
+	ChannelKindIncrementalTicker          ChannelKind = "incremental_ticker"
+	ChannelKindInstrumentState            ChannelKind = "instrument.state"
+	ChannelKindMarkPriceOptions           ChannelKind = "markprice.options"
+	ChannelKindPerpetual                  ChannelKind = "perpetual"
+	ChannelKindPlatformState              ChannelKind = "platform_state"
+	ChannelKindPlatformStatePublicMethods ChannelKind = "platform_state.public_methods_state"
+	ChannelKindQuote                      ChannelKind = "quote"
+	ChannelKindTicker                     ChannelKind = "ticker"
+	ChannelKindTrades                     ChannelKind = "trades"
+)
+
+// Private channels
+const (
+	ChannelKindBlockTradeConfirmations ChannelKind = "block_trade_confirmations"
+	ChannelKindUserAccessLog           ChannelKind = "user.access_log"
+	ChannelKindUserChanges             ChannelKind = "user.changes"
+	ChannelKindUserLock                ChannelKind = "user.lock"
+	ChannelKindUserMMPTrigger          ChannelKind = "user.mmp_trigger"
+	ChannelKindUserOrders              ChannelKind = "user.orders"
+	ChannelKindUserPortfolio           ChannelKind = "user.portfolio"
+	ChannelKindUserTrades              ChannelKind = "user.trades"
+)
+
+// Notification intervals
+const (
+	IntervalRaw   = "raw"
+	Interval100ms = "100ms"
+	IntervalAgg2  = "agg2"
+)
+
+// Instrument kinds of the channels by kind and currency
+const (
+	InstrumentKindFuture      = "future"
+	InstrumentKindOption      = "option"
+	InstrumentKindSpot        = "spot"
+	InstrumentKindFutureCombo = "future_combo"
+	InstrumentKindOptionCombo = "option_combo"
+	InstrumentKindCombo       = "combo"
+	InstrumentKindAny         = "any"
+)
+
+// CurrencyAny selects every currency in the channels by kind and currency
+const CurrencyAny = "any"
+
+// Channel is a parsed subscription channel. Only the fields used by its Kind
+// are set, e.g. InstrumentName and Interval for ticker.{instrument_name}.{interval}.
+type Channel struct {
+	Kind           ChannelKind
+	InstrumentName string
+	// InstrumentKind and Currency select the instruments of the channels by
+	// kind and currency, e.g. user.orders.{kind}.{currency}.{interval}
+	InstrumentKind string
+	Currency       string
+	IndexName      string
+	Interval       string
+	// Group and Depth are set for grouped order books
+	Group string
+	Depth int
+	// Resolution is the candle resolution of chart.trades
+	Resolution string
+}
+
+func AnnouncementsChannel() Channel {
+	return Channel{Kind: ChannelKindAnnouncements}
+}
+
+func BookChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindBook, InstrumentName: instrumentName, Interval: interval}
+}
+
+func BookGroupChannel(instrumentName, group string, depth int, interval string) Channel {
+	return Channel{Kind: ChannelKindBook, InstrumentName: instrumentName, Group: group, Depth: depth, Interval: interval}
+}
+
+func ChartTradesChannel(instrumentName, resolution string) Channel {
+	return Channel{Kind: ChannelKindChartTrades, InstrumentName: instrumentName, Resolution: resolution}
+}
+
+func PriceIndexChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindPriceIndex, IndexName: indexName}
+}
+
+func PriceRankingChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindPriceRanking, IndexName: indexName}
+}
+
+func PriceStatisticsChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindPriceStatistics, IndexName: indexName}
+}
+
+func VolatilityIndexChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindVolatilityIndex, IndexName: indexName}
+}
+
+func EstimatedExpirationPriceChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindEstimatedExpirationPrice, IndexName: indexName}
+}
+
+func IncrementalTickerChannel(instrumentName string) Channel {
+	return Channel{Kind: ChannelKindIncrementalTicker, InstrumentName: instrumentName}
+}
+
+func InstrumentStateChannel(instrumentKind, currency string) Channel {
+	return Channel{Kind: ChannelKindInstrumentState, InstrumentKind: instrumentKind, Currency: currency}
+}
+
+func MarkPriceOptionsChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindMarkPriceOptions, IndexName: indexName}
+}
+
+func PerpetualChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindPerpetual, InstrumentName: instrumentName, Interval: interval}
+}
+
+func PlatformStateChannel() Channel {
+	return Channel{Kind: ChannelKindPlatformState}
+}
+
+func PlatformStatePublicMethodsChannel() Channel {
+	return Channel{Kind: ChannelKindPlatformStatePublicMethods}
+}
+
+func QuoteChannel(instrumentName string) Channel {
+	return Channel{Kind: ChannelKindQuote, InstrumentName: instrumentName}
+}
+
+func TickerChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindTicker, InstrumentName: instrumentName, Interval: interval}
+}
+
+func TradesChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindTrades, InstrumentName: instrumentName, Interval: interval}
+}
+
+func TradesByKindChannel(instrumentKind, currency, interval string) Channel {
+	return Channel{Kind: ChannelKindTrades, InstrumentKind: instrumentKind, Currency: currency, Interval: interval}
+}
+
+// BlockTradeConfirmationsChannel returns the channel of all currencies when
+// currency is empty
+func BlockTradeConfirmationsChannel(currency string) Channel {
+	return Channel{Kind: ChannelKindBlockTradeConfirmations, Currency: currency}
+}
+
+func UserAccessLogChannel() Channel {
+	return Channel{Kind: ChannelKindUserAccessLog}
+}
+
+func UserChangesChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindUserChanges, InstrumentName: instrumentName, Interval: interval}
+}
+
+func UserChangesByKindChannel(instrumentKind, currency, interval string) Channel {
+	return Channel{Kind: ChannelKindUserChanges, InstrumentKind: instrumentKind, Currency: currency, Interval: interval}
+}
+
+func UserLockChannel() Channel {
+	return Channel{Kind: ChannelKindUserLock}
+}
+
+func UserMMPTriggerChannel(indexName string) Channel {
+	return Channel{Kind: ChannelKindUserMMPTrigger, IndexName: indexName}
+}
+
+func UserOrdersChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindUserOrders, InstrumentName: instrumentName, Interval: interval}
+}
+
+func UserOrdersByKindChannel(instrumentKind, currency, interval string) Channel {
+	return Channel{Kind: ChannelKindUserOrders, InstrumentKind: instrumentKind, Currency: currency, Interval: interval}
+}
+
+func UserPortfolioChannel(currency string) Channel {
+	return Channel{Kind: ChannelKindUserPortfolio, Currency: currency}
+}
+
+func UserTradesChannel(instrumentName, interval string) Channel {
+	return Channel{Kind: ChannelKindUserTrades, InstrumentName: instrumentName, Interval: interval}
+}
+
+func UserTradesByKindChannel(instrumentKind, currency, interval string) Channel {
+	return Channel{Kind: ChannelKindUserTrades, InstrumentKind: instrumentKind, Currency: currency, Interval: interval}
+}
+
+// Channels returns the names of channels, for DeribitWSClient.Subscribe
+func Channels(channels ...Channel) []string {
+	names := make([]string, len(channels))
+	for i, channel := range channels {
+		names[i] = channel.String()
+	}
+	return names
+}
+
+// Private reports whether the channel needs an authenticated connection
+func (c Channel) Private() bool {
+	return c.Kind == ChannelKindBlockTradeConfirmations || strings.HasPrefix(string(c.Kind), "user.")
+}
+
+// Grouped reports whether c is a grouped order book channel
+func (c Channel) Grouped() bool {
+	return c.Kind == ChannelKindBook && (c.Group != "" || c.Depth != 0)
+}
+
+// String returns the channel name
+func (c Channel) String() string {
+	kind := string(c.Kind)
+
+	switch c.Kind {
+	case ChannelKindBook:
+		if c.Grouped() {
+			return join(kind, c.InstrumentName, c.Group, strconv.Itoa(c.Depth), c.Interval)
+		}
+		return join(kind, c.InstrumentName, c.Interval)
+	case ChannelKindChartTrades:
+		return join(kind, c.InstrumentName, c.Resolution)
+	case ChannelKindPriceIndex, ChannelKindPriceRanking, ChannelKindPriceStatistics, ChannelKindVolatilityIndex,
+		ChannelKindEstimatedExpirationPrice, ChannelKindMarkPriceOptions, ChannelKindUserMMPTrigger:
+		return join(kind, c.IndexName)
+	case ChannelKindIncrementalTicker, ChannelKindQuote:
+		return join(kind, c.InstrumentName)
+	case ChannelKindInstrumentState:
+		return join(kind, c.InstrumentKind, c.Currency)
+	case ChannelKindPerpetual, ChannelKindTicker:
+		return join(kind, c.InstrumentName, c.Interval)
+	case ChannelKindTrades, ChannelKindUserChanges, ChannelKindUserOrders, ChannelKindUserTrades:
+		if c.InstrumentName != "" {
+			return join(kind, c.InstrumentName, c.Interval)
+		}
+		return join(kind, c.InstrumentKind, c.Currency, c.Interval)
+	case ChannelKindUserPortfolio:
+		return join(kind, c.Currency)
+	case ChannelKindBlockTradeConfirmations:
+		if c.Currency != "" {
+			return join(kind, c.Currency)
+		}
+	}
+
+	return kind
+}
+
+func join(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+// ParseChannel parses a channel name, names of unknown channels or with a
+// wrong number of parts are reported with ErrInvalidChannel
+func ParseChannel(name string) (Channel, error) {
+	channel, ok := parseChannel(name)
+	if !ok {
+		return Channel{}, fmt.Errorf("%w: %q", ErrInvalidChannel, name)
+	}
+	return channel, nil
+}
+
+func parseChannel(name string) (Channel, bool) {
+	parts := strings.Split(name, ".")
+	n := len(parts)
+
+	for _, part := range parts {
+		if part == "" {
+			return Channel{}, false
+		}
+	}
+
+	switch parts[0] {
+	case "announcements":
+		if n == 1 {
+			return AnnouncementsChannel(), true
+		}
+	case "platform_state":
+		if n == 1 {
+			return PlatformStateChannel(), true
+		}
+		if n == 2 && parts[1] == "public_methods_state" {
+			return PlatformStatePublicMethodsChannel(), true
+		}
+	case "book":
+		switch n {
+		case 3:
+			return BookChannel(parts[1], parts[2]), true
+		case 5:
+			depth, err := strconv.Atoi(parts[3])
+			if err != nil || depth <= 0 {
+				return Channel{}, false
+			}
+			return BookGroupChannel(parts[1], parts[2], depth, parts[4]), true
+		}
+	case "chart":
+		if n == 4 && parts[1] == "trades" {
+			return ChartTradesChannel(parts[2], parts[3]), true
+		}
+	case "deribit_price_index", "deribit_price_ranking", "deribit_price_statistics",
+		"deribit_volatility_index", "estimated_expiration_price":
+		if n == 2 {
+			return Channel{Kind: ChannelKind(parts[0]), IndexName: parts[1]}, true
+		}
+	case "markprice":
+		if n == 3 && parts[1] == "options" {
+			return MarkPriceOptionsChannel(parts[2]), true
+		}
+	case "incremental_ticker", "quote":
+		if n == 2 {
+			return Channel{Kind: ChannelKind(parts[0]), InstrumentName: parts[1]}, true
+		}
+	case "instrument":
+		if n == 4 && parts[1] == "state" {
+			return InstrumentStateChannel(parts[2], parts[3]), true
+		}
+	case "perpetual", "ticker":
+		if n == 3 {
+			return Channel{Kind: ChannelKind(parts[0]), InstrumentName: parts[1], Interval: parts[2]}, true
+		}
+	case "trades":
+		return parseInstrumentOrKind(ChannelKindTrades, parts[1:])
+	case "block_trade_confirmations":
+		switch n {
+		case 1:
+			return BlockTradeConfirmationsChannel(""), true
+		case 2:
+			return BlockTradeConfirmationsChannel(parts[1]), true
+		}
+	case "user":
+		if n < 2 {
+			break
+		}
+		kind := ChannelKind("user." + parts[1])
+		switch kind {
+		case ChannelKindUserAccessLog, ChannelKindUserLock:
+			if n == 2 {
+				return Channel{Kind: kind}, true
+			}
+		case ChannelKindUserMMPTrigger:
+			if n == 3 {
+				return UserMMPTriggerChannel(parts[2]), true
+			}
+		case ChannelKindUserPortfolio:
+			if n == 3 {
+				return UserPortfolioChannel(parts[2]), true
+			}
+		case ChannelKindUserChanges, ChannelKindUserOrders, ChannelKindUserTrades:
+			return parseInstrumentOrKind(kind, parts[2:])
+		}
+	}
+
+	return Channel{}, false
+}
+
+// parseInstrumentOrKind parses the {instrument_name}.{interval} or
+// {kind}.{currency}.{interval} parts of a channel name
+func parseInstrumentOrKind(kind ChannelKind, parts []string) (Channel, bool) {
+	switch len(parts) {
+	case 2:
+		return Channel{Kind: kind, InstrumentName: parts[0], Interval: parts[1]}, true
+	case 3:
+		return Channel{Kind: kind, InstrumentKind: parts[0], Currency: parts[1], Interval: parts[2]}, true
+	}
+	return Channel{}, false
+}
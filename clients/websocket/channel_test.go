@@ -0,0 +1,129 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		private bool
+	}{
+		{"announcements", AnnouncementsChannel(), false},
+		{"book.BTC-PERPETUAL.raw", BookChannel("BTC-PERPETUAL", IntervalRaw), false},
+		{"book.BTC-PERPETUAL.100ms", BookChannel("BTC-PERPETUAL", Interval100ms), false},
+		{"book.ETH-PERPETUAL.none.10.100ms", BookGroupChannel("ETH-PERPETUAL", "none", 10, Interval100ms), false},
+		{"chart.trades.BTC-PERPETUAL.60", ChartTradesChannel("BTC-PERPETUAL", "60"), false},
+		{"deribit_price_index.btc_usd", PriceIndexChannel("btc_usd"), false},
+		{"deribit_price_ranking.btc_usd", PriceRankingChannel("btc_usd"), false},
+		{"deribit_price_statistics.btc_usd", PriceStatisticsChannel("btc_usd"), false},
+		{"deribit_volatility_index.btc_usd", VolatilityIndexChannel("btc_usd"), false},
+		{"estimated_expiration_price.btc_usd", EstimatedExpirationPriceChannel("btc_usd"), false},
+		{"incremental_ticker.BTC-PERPETUAL", IncrementalTickerChannel("BTC-PERPETUAL"), false},
+		{"instrument.state.future.BTC", InstrumentStateChannel(InstrumentKindFuture, "BTC"), false},
+		{"markprice.options.btc_usd", MarkPriceOptionsChannel("btc_usd"), false},
+		{"perpetual.BTC-PERPETUAL.raw", PerpetualChannel("BTC-PERPETUAL", IntervalRaw), false},
+		{"platform_state", PlatformStateChannel(), false},
+		{"platform_state.public_methods_state", PlatformStatePublicMethodsChannel(), false},
+		{"quote.BTC-PERPETUAL", QuoteChannel("BTC-PERPETUAL"), false},
+		{"ticker.BTC-27DEC24-50000-C.100ms", TickerChannel("BTC-27DEC24-50000-C", Interval100ms), false},
+		{"trades.BTC-PERPETUAL.raw", TradesChannel("BTC-PERPETUAL", IntervalRaw), false},
+		{"trades.option.any.agg2", TradesByKindChannel(InstrumentKindOption, CurrencyAny, IntervalAgg2), false},
+		{"block_trade_confirmations", BlockTradeConfirmationsChannel(""), true},
+		{"block_trade_confirmations.BTC", BlockTradeConfirmationsChannel("BTC"), true},
+		{"user.access_log", UserAccessLogChannel(), true},
+		{"user.changes.BTC-PERPETUAL.raw", UserChangesChannel("BTC-PERPETUAL", IntervalRaw), true},
+		{"user.changes.future.BTC.raw", UserChangesByKindChannel(InstrumentKindFuture, "BTC", IntervalRaw), true},
+		{"user.lock", UserLockChannel(), true},
+		{"user.mmp_trigger.btc_usd", UserMMPTriggerChannel("btc_usd"), true},
+		{"user.orders.BTC-PERPETUAL.raw", UserOrdersChannel("BTC-PERPETUAL", IntervalRaw), true},
+		{"user.orders.future.BTC.100ms", UserOrdersByKindChannel(InstrumentKindFuture, "BTC", Interval100ms), true},
+		{"user.portfolio.btc", UserPortfolioChannel("btc"), true},
+		{"user.trades.BTC-PERPETUAL.raw", UserTradesChannel("BTC-PERPETUAL", IntervalRaw), true},
+		{"user.trades.future.BTC.100ms", UserTradesByKindChannel(InstrumentKindFuture, "BTC", Interval100ms), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, err := ParseChannel(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.channel, channel)
+			assert.Equal(t, tt.name, channel.String())
+			assert.Equal(t, tt.private, channel.Private())
+			assert.Equal(t, tt.private, isPrivateChannel(tt.name))
+		})
+	}
+}
+
+func TestParseChannel_Invalid(t *testing.T) {
+	for _, name := range []string{
+		"",
+		"unknown.channel",
+		"announcements.extra",
+		"book.BTC-PERPETUAL",
+		"book.BTC-PERPETUAL.none.x.100ms",
+		"book..raw",
+		"ticker.BTC-PERPETUAL",
+		"user",
+		"user.orders.BTC-PERPETUAL",
+		"user.unknown.BTC",
+		"chart.candles.BTC-PERPETUAL.60",
+	} {
+		_, err := ParseChannel(name)
+		assert.True(t, errors.Is(err, ErrInvalidChannel), name)
+	}
+}
+
+func TestChannelBuilders(t *testing.T) {
+	assert.Equal(t, "book.BTC-PERPETUAL.none.1.100ms", ChannelBookGroup("BTC-PERPETUAL", "", 0, ""))
+	assert.Equal(t, "user.mmp_trigger.btc_usd", ChannelUserMMPTrigger("btc_usd"))
+	assert.Equal(t, "deribit_volatility_index.eth_usd", ChannelDeribitVolatilityIndex(DERIBIT_VOLATILITY_INDEX_NAME_ETH))
+	assert.Equal(t, []string{"quote.BTC-PERPETUAL", "user.lock"}, Channels(QuoteChannel("BTC-PERPETUAL"), UserLockChannel()))
+}
+
+func TestSubscriptionsProcess_Routing(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	ctx := context.Background()
+
+	group := BookGroupChannel("BTC-PERPETUAL", "none", 10, Interval100ms).String()
+	groupSub, err := Subscribe[*models.OrderBookGroupNotification](ctx, client, group)
+	require.NoError(t, err)
+	defer groupSub.Close()
+
+	raw := BookChannel("BTC-PERPETUAL", IntervalRaw).String()
+	rawSub, err := Subscribe[*models.OrderBookRawNotification](ctx, client, raw)
+	require.NoError(t, err)
+	defer rawSub.Close()
+
+	unknown := "rfq.btc"
+	unknownSub, err := Subscribe[json.RawMessage](ctx, client, unknown)
+	require.NoError(t, err)
+	defer unknownSub.Close()
+
+	s.Publish(group, map[string]interface{}{"change_id": 1})
+	s.Publish(raw, map[string]interface{}{"change_id": 2})
+	s.Publish(unknown, map[string]interface{}{"state": "created"})
+
+	assert.Equal(t, int64(1), receive(t, groupSub.C()).ChangeID)
+	assert.Equal(t, int64(2), receive(t, rawSub.C()).ChangeID)
+	assert.JSONEq(t, `{"state":"created"}`, string(receive(t, unknownSub.C())))
+}
+
+func TestSubscribe_BlockTradeConfirmationsIsPrivate(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, withCredential)
+
+	_, err := client.Subscribe([]string{BlockTradeConfirmationsChannel("").String()})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Calls("private/subscribe"))
+	assert.Zero(t, s.Calls("public/subscribe"))
+}
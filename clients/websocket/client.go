@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -23,29 +23,70 @@ import (
 var (
 	ErrUnAuthorized          = errors.New("websocket unauthorized")
 	ErrWebsocketNotConnected = errors.New("websocket not connected")
+	ErrClientClosed          = errors.New("websocket client closed")
+	ErrScopeNotGranted       = errors.New("websocket scope not granted")
 )
 
 type DeribitWSClient struct {
 	ctx context.Context
 	cfg *deribit.WebsocketConfiguration
 
+	// stopCtx is cancelled by Close, it stops reconnecting and the background
+	// goroutines tracked by wg
+	stopCtx context.Context
+	stop    context.CancelFunc
+	closed  bool
+	spawnMu sync.Mutex
+	wg      sync.WaitGroup
+	// shuttingDown is set by the first Shutdown, before its requests, so a
+	// concurrent Shutdown returns ErrClientClosed
+	shuttingDown bool
+
 	client *http.Client
 
+	// auth, guarded by authMu
 	credential     deribit.Credential
+	authMu         sync.RWMutex
 	authentication *websocketmodels.Authentication
-
-	// conn
-	conn            *websocket.Conn
-	rpcConn         *jsonrpc2.Conn
-	stopheartbeatch chan struct{}
-	connected       atomic.Bool
-
-	// subs
-	subscriptions    []string
+	// switches are the ExchangeToken and ForkToken calls applied again after
+	// a login with the credential
+	switches []sessionSwitch
+	// authUpdated wakes the token refresh routine, refreshMu serializes
+	// token refreshes
+	authUpdated chan struct{}
+	refreshMu   sync.Mutex
+	// loggedOut stops re-authentication with the configured credential after
+	// Logout until the next successful authentication
+	loggedOut atomic.Bool
+
+	// conn, connectMu serializes dialing between Start and the reconnect
+	// routine, connMu guards the current conn
+	connectMu     sync.Mutex
+	connMu        sync.RWMutex
+	conn          *websocket.Conn
+	rpcConn       *jsonrpc2.Conn
+	connected     atomic.Bool
+	lastHeartbeat atomic.Int64
+
+	// subs, guarded by subsMu. subscriptions holds the requested channels and
+	// subscriptionsMap the ones confirmed on the current connection, which is
+	// counted by subscriptionsGen
+	subsMu           sync.Mutex
+	subscriptions    map[string]struct{}
 	subscriptionsMap map[string]struct{}
+	subscriptionsGen uint64
+	// subscribeLimiter paces subscribe requests, shared by the clients of a Pool
+	subscribeLimiter *deribit.RateLimiter
 
 	// pub/sub
-	emitter *emission.Emitter
+	emitter    *emission.Emitter
+	streams    *streamRegistry
+	matchers   *matcherSet
+	deliveries *deliveryRegistry
+	decoders   *decoderRegistry
+	// forward passes notifications on to the Pool of the client
+	forward func(channel string, notification interface{}, data json.RawMessage, received time.Time)
+	cache   *notificationCache
 
 	logger *logrus.Logger
 }
@@ -56,14 +97,26 @@ func NewDeribitWsClient(cfg *deribit.Configuration) *DeribitWSClient {
 		ctx = context.Background()
 	}
 
+	stopCtx, stop := context.WithCancel(ctx)
+
 	client := &DeribitWSClient{
 		ctx:              ctx,
+		stopCtx:          stopCtx,
+		stop:             stop,
 		cfg:              cfg.WebsocketConfiguration,
 		client:           cfg.Client,
 		credential:       cfg.Credential,
 		logger:           cfg.Logger,
+		authUpdated:      make(chan struct{}, 1),
+		subscriptions:    make(map[string]struct{}),
 		subscriptionsMap: make(map[string]struct{}),
+		subscribeLimiter: newSubscribeLimiter(cfg.WebsocketConfiguration),
 		emitter:          emission.NewEmitter(),
+		streams:          newStreamRegistry(),
+		matchers:         newMatcherSet(),
+		deliveries:       newDeliveryRegistry(),
+		decoders:         newDecoderRegistry(),
+		cache:            newNotificationCache(),
 	}
 
 	if cfg.AutoStart {
@@ -77,6 +130,10 @@ func NewDeribitWsClient(cfg *deribit.Configuration) *DeribitWSClient {
 }
 
 func (c *DeribitWSClient) Start(ctx context.Context) error {
+	if c.stopCtx.Err() != nil {
+		return ErrClientClosed
+	}
+
 	if c.IsConnected() {
 		return nil
 	}
@@ -84,6 +141,35 @@ func (c *DeribitWSClient) Start(ctx context.Context) error {
 	return c.start(ctx)
 }
 
+func (c *DeribitWSClient) setConn(conn *websocket.Conn, rpcConn *jsonrpc2.Conn) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.conn, c.rpcConn = conn, rpcConn
+}
+
+func (c *DeribitWSClient) getAuthentication() *websocketmodels.Authentication {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	return c.authentication
+}
+
+func (c *DeribitWSClient) setAuthentication(auth *websocketmodels.Authentication) {
+	c.authMu.Lock()
+	c.authentication = auth
+	c.authMu.Unlock()
+
+	select {
+	case c.authUpdated <- struct{}{}:
+	default:
+	}
+}
+
+func (c *DeribitWSClient) getRPCConn() *jsonrpc2.Conn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.rpcConn
+}
+
 // setIsConnected sets state for isConnoected
 func (c *DeribitWSClient) setIsConnected(state bool) {
 	c.connected.Store(state)
@@ -94,122 +180,170 @@ func (c *DeribitWSClient) IsConnected() bool {
 	return c.connected.Load()
 }
 
-func (c *DeribitWSClient) Subscribe(channels []string) {
-	c.subscriptions = append(c.subscriptions, channels...)
-	c.subscribe()
-}
-
-func (c *DeribitWSClient) subscribe() {
-	var publicChannels []string
-	var privateChannels []string
-
-	for _, v := range c.subscriptions {
-		if _, ok := c.subscriptionsMap[v]; ok {
-			continue
-		}
-		if strings.HasPrefix(v, "user.") {
-			privateChannels = append(privateChannels, v)
-		} else {
-			publicChannels = append(publicChannels, v)
-		}
-	}
+func (c *DeribitWSClient) start(ctx context.Context) error {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
 
-	if len(publicChannels) > 0 {
-		_, err := c.PublicSubscribe(&models.SubscribeParams{
-			Channels: publicChannels,
-		})
-		if err != nil {
-			return
-		}
-	}
-	if len(privateChannels) > 0 {
-		_, err := c.PrivateSubscribe(&models.SubscribeParams{
-			Channels: privateChannels,
-		})
-		if err != nil {
-			return
-		}
+	// a concurrent Start or the reconnect routine may have won the race
+	if c.IsConnected() {
+		return nil
 	}
 
-	allChannels := append(publicChannels, privateChannels...)
-	for _, v := range allChannels {
-		c.subscriptionsMap[v] = struct{}{}
-	}
+	return c.connect(ctx, false)
 }
 
-func (c *DeribitWSClient) start(ctx context.Context) error {
-	ctx = context.WithoutCancel(ctx)
-
+// connect dials and sets up the session until the reconnect policy gives up
+// or ctx is done. Reconnects wait for the first retry delay before dialing and
+// retry failed setups as well, e.g. a rejected auth. The caller must hold
+// connectMu.
+func (c *DeribitWSClient) connect(ctx context.Context, reconnecting bool) error {
 	c.setIsConnected(false)
-	c.subscriptionsMap = make(map[string]struct{})
-	c.conn = nil
-	c.rpcConn = nil
+	c.resetActiveSubscriptions()
+	c.setConn(nil, nil)
 
-	for i := 0; i < deribit.MaxTryTimes; i++ {
-		conn, _, err := c.dial(ctx)
-		if err != nil {
-			dur := time.Duration((i+1)*5) * time.Second
+	policy := c.reconnectPolicy()
+
+	var lastErr error = ErrWebsocketNotConnected
+	retry := 0
+	if reconnecting {
+		retry = 1
+	}
+
+	for {
+		if retry > 0 {
+			delay, ok := policy.NextDelay(retry)
+			if !ok {
+				c.logger.WithContext(ctx).Warnf("websocket dial gave up after %d retries: %v\n", retry-1, lastErr)
+				c.emitConnectionEvent(EventGaveUp, websocketmodels.ConnectionEvent{Attempt: retry - 1, Err: lastErr})
+				policy.GiveUp(retry-1, lastErr)
+				return fmt.Errorf("websocket dial: gave up after %d retries: %w", retry-1, lastErr)
+			}
 
 			c.logger.
 				WithContext(ctx).
-				Warnf("websocket dial fail(%d), and will retry in %s\n", i, dur)
+				Warnf("websocket dial fail(%v), retry(%d) in %s\n", lastErr, retry, delay)
+			c.emitConnectionEvent(EventReconnecting, websocketmodels.ConnectionEvent{Attempt: retry, Delay: delay, Err: lastErr})
+
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-c.stopCtx.Done():
+				t.Stop()
+				return ErrClientClosed
+			}
+		}
 
-			time.Sleep(dur)
+		c.emitConnectionEvent(EventConnecting, websocketmodels.ConnectionEvent{Attempt: retry})
 
-			continue
+		conn, _, err := c.dial(ctx)
+		if err == nil {
+			err = c.setup(context.WithoutCancel(ctx), conn)
+			if err == nil || !reconnecting || errors.Is(err, ErrClientClosed) {
+				return err
+			}
 		}
 
-		c.conn = conn
-		break
-	}
-
-	if c.conn == nil {
-		return errors.New("websocket dial fail")
+		lastErr = err
+		retry++
 	}
+}
 
+// setup runs the session on a freshly dialed conn
+func (c *DeribitWSClient) setup(ctx context.Context, conn *websocket.Conn) error {
 	// Create a new object stream with the websocket connection
-	stream := websocketmodels.NewObjectStream(c.conn)
+	stream := websocketmodels.NewObjectStream(conn, c.cfg.FrameInterceptors...)
 
 	// Initialize the JSON-RPC connection with the stream
-	c.rpcConn = jsonrpc2.NewConn(ctx, stream, c)
+	rpcConn := jsonrpc2.NewConn(ctx, stream, c)
+
+	// Shutdown either sees the new conn or setup sees the client stopped
+	c.connMu.Lock()
+	if c.stopCtx.Err() != nil {
+		c.connMu.Unlock()
+		_ = rpcConn.Close()
+		return ErrClientClosed
+	}
+	c.conn, c.rpcConn = conn, rpcConn
+	c.connMu.Unlock()
 
 	c.setIsConnected(true)
+	c.lastHeartbeat.Store(time.Now().UnixNano())
+	c.emitConnectionEvent(EventConnected, websocketmodels.ConnectionEvent{})
 
-	// Authenticate if credentials are provided
-	if c.credential.ApiKey != "" && c.credential.SecretKey != "" {
-		if err := c.Auth(c.credential.ApiKey, c.credential.SecretKey); err != nil {
-			return fmt.Errorf("auth: %w", err)
-		}
+	// Authenticate if credentials are provided, or renew the session of the
+	// previous connection
+	if err := c.authenticate(ctx); err != nil {
+		return c.teardown(conn, rpcConn, fmt.Errorf("auth: %w", err))
 	}
 
 	// Subscribe to channels
-	c.subscribe()
+	c.subscribe(ctx)
 
 	// Set heartbeat
-	_, err := c.SetHeartbeat(&models.SetHeartbeatParams{Interval: c.cfg.HeartBeatInterval})
+	_, err := c.SetHeartbeatContext(ctx, &models.SetHeartbeatParams{Interval: c.cfg.HeartBeatInterval})
 	if err != nil {
-		return fmt.Errorf("set heartbeat: %w", err)
+		return c.teardown(conn, rpcConn, fmt.Errorf("set heartbeat: %w", err))
 	}
 
 	// Start reconnection handler if enabled
 	if c.cfg.AutoReconnect {
-		go c.reconnect(ctx)
+		c.spawn(func() { c.reconnect(ctx, rpcConn) })
 	}
 
-	// Start heartbeat routine
-	go c.startheartbeat(ctx)
+	// Start heartbeat monitor
+	c.spawn(func() { c.monitorHeartbeat(ctx, conn, rpcConn) })
+
+	// Start token refresh routine
+	c.spawn(func() { c.refreshTokenLoop(ctx, rpcConn) })
 
 	return nil
 }
 
+// teardown closes the conn of a failed setup and marks the client
+// disconnected, it returns err
+func (c *DeribitWSClient) teardown(conn *websocket.Conn, rpcConn *jsonrpc2.Conn, err error) error {
+	c.connMu.Lock()
+	if c.rpcConn == rpcConn {
+		c.conn, c.rpcConn = nil, nil
+	}
+	c.connMu.Unlock()
+
+	c.setIsConnected(false)
+	_ = rpcConn.Close()
+	_ = conn.CloseNow()
+	c.emitConnectionEvent(EventDisconnected, websocketmodels.ConnectionEvent{Err: err})
+	return err
+}
+
+func (c *DeribitWSClient) reconnectPolicy() deribit.ReconnectPolicy {
+	if c.cfg.ReconnectPolicy != nil {
+		return c.cfg.ReconnectPolicy
+	}
+
+	policy := deribit.DefaultReconnectPolicy()
+	if c.cfg.ReconnectDuration > 0 {
+		policy.InitialInterval = c.cfg.ReconnectDuration
+	}
+	return policy
+}
+
 // Call issues JSONRPC v2 calls
 func (c *DeribitWSClient) Call(method string, params interface{}, result interface{}) (err error) {
+	return c.CallContext(c.defaultContext(), method, params, result)
+}
+
+// CallContext issues JSONRPC v2 calls bound to ctx. The call is abandoned as
+// soon as ctx is done; CallTimeout still caps calls without an earlier deadline.
+func (c *DeribitWSClient) CallContext(ctx context.Context, method string, params interface{}, result interface{}) (err error) {
 	timeout := c.cfg.CallTimeout
 	if timeout == 0 {
 		timeout = time.Minute
 	}
 
-	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.ctx), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	defer func() {
@@ -218,6 +352,30 @@ func (c *DeribitWSClient) Call(method string, params interface{}, result interfa
 		}
 	}()
 
+	if err = c.checkScope(method); err != nil {
+		return err
+	}
+
+	// a private call rejected for an expired token is retried once with a
+	// refreshed token
+	token := c.accessToken()
+	err = c.call(ctx, method, params, result)
+	if err != nil && isPrivateMethod(method) && isUnauthorizedError(err) {
+		if refreshErr := c.refreshAuthentication(ctx, token); refreshErr == nil {
+			err = c.call(ctx, method, params, result)
+		} else {
+			c.logger.WithContext(ctx).Warnln("token refresh fail", refreshErr)
+		}
+	}
+
+	return err
+}
+
+func (c *DeribitWSClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if c.stopCtx.Err() != nil {
+		return ErrClientClosed
+	}
+
 	if !c.IsConnected() {
 		return ErrWebsocketNotConnected
 	}
@@ -227,23 +385,38 @@ func (c *DeribitWSClient) Call(method string, params interface{}, result interfa
 	}
 
 	if token, ok := params.(websocketmodels.PrivateParams); ok {
-		if c.authentication == nil || c.authentication.AccessToken == "" {
+		auth := c.getAuthentication()
+		if auth == nil || auth.AccessToken == "" {
 			return ErrUnAuthorized
 		}
 
-		token.SetToken(c.authentication.AccessToken)
+		token.SetToken(auth.AccessToken)
+	}
+
+	rpcConn := c.getRPCConn()
+	if rpcConn == nil {
+		return ErrWebsocketNotConnected
 	}
 
-	if err := c.rpcConn.Call(ctx, method, params, result); err != nil {
+	if err := rpcConn.Call(ctx, method, params, result); err != nil {
 		return fmt.Errorf("jsonrpc call: %w", err)
 	} else {
 		return nil
 	}
 }
 
+// defaultContext is used by the methods without a context argument; it keeps
+// the values of the configured context but not its cancellation
+func (c *DeribitWSClient) defaultContext() context.Context {
+	return context.WithoutCancel(c.ctx)
+}
+
 // Handle implements jsonrpc2.Handler
 func (c *DeribitWSClient) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
-	if req.Method == "subscription" {
+	switch req.Method {
+	case "heartbeat":
+		c.heartbeatProcess(ctx, req)
+	case "subscription":
 		// update events
 		if req.Params != nil && len(*req.Params) > 0 {
 			var event websocketmodels.Event
@@ -252,53 +425,34 @@ func (c *DeribitWSClient) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *
 				return
 			}
 
-			if err := c.subscriptionsProcess(&event); err != nil {
-				c.logger.WithContext(ctx).Warnln("websocket subscription process fail", err)
-			}
+			c.subscriptionsProcess(ctx, &event)
 		}
 	}
 }
 
-func (c *DeribitWSClient) startheartbeat(ctx context.Context) {
-	c.stopheartbeatch = make(chan struct{})
-
-	t := time.NewTicker(c.cfg.TestDuration)
-	for {
-		select {
-		case <-t.C:
-			if _, err := c.Test(); err != nil {
-				c.logger.WithContext(ctx).Warnln("heartbeat test fail", err)
-				return
-			}
-		case <-c.stopheartbeatch:
-			c.logger.WithContext(ctx).Debugln("heartbeat stop")
-			return
-		case <-ctx.Done():
-			c.logger.WithContext(ctx).Debugln("heartbeat ctx done")
-			return
-		}
+func (c *DeribitWSClient) reconnect(ctx context.Context, rpcConn *jsonrpc2.Conn) {
+	select {
+	case <-rpcConn.DisconnectNotify():
+	case <-c.stopCtx.Done():
+		return
 	}
-}
 
-func (c *DeribitWSClient) stopheartbeat() {
-	if c.stopheartbeatch != nil {
-		close(c.stopheartbeatch)
-	}
-}
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
 
-func (c *DeribitWSClient) reconnect(ctx context.Context) {
-	notify := c.rpcConn.DisconnectNotify()
-	<-notify
+	// the conn was replaced by a concurrent Start meanwhile
+	if c.getRPCConn() != rpcConn {
+		return
+	}
 
-	c.stopheartbeat()
+	c.setIsConnected(false)
+	c.emitConnectionEvent(EventDisconnected, websocketmodels.ConnectionEvent{Err: ErrWebsocketNotConnected})
 
 	c.logger.WithContext(ctx).Debugln("jsonrpc conn disconnected, reconnecting...")
 
-	if c.cfg.ReconnectDuration > 0 {
-		time.Sleep(c.cfg.ReconnectDuration)
-	}
-
-	err := c.start(ctx)
+	// the dial loop follows stopCtx, so cancelling the configured context or
+	// closing the client stops reconnecting
+	err := c.connect(c.stopCtx, true)
 	if err != nil {
 		c.logger.WithContext(ctx).Warnln("reconnect fail", err)
 	} else {
@@ -307,12 +461,16 @@ func (c *DeribitWSClient) reconnect(ctx context.Context) {
 }
 
 func (c *DeribitWSClient) dial(ctx context.Context) (*websocket.Conn, *http.Response, error) {
-	if c.client != nil && c.client.Timeout == 0 {
-		c.client.Timeout = c.cfg.DialWebsocketTimeout
+	// the configured client may be shared, so the timeout is set on a copy
+	client := c.client
+	if client != nil && client.Timeout == 0 && c.cfg.DialWebsocketTimeout > 0 {
+		cp := *client
+		cp.Timeout = c.cfg.DialWebsocketTimeout
+		client = &cp
 	}
 
 	conn, resp, err := websocket.Dial(ctx, c.cfg.Url, &websocket.DialOptions{
-		HTTPClient: c.client,
+		HTTPClient: client,
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("websocket dial: %w", err)
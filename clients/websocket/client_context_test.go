@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallContext_Cancel(t *testing.T) {
+	s := newMockServer(t)
+	release := make(chan struct{})
+	defer close(release)
+	s.Handle("public/get_time", func(json.RawMessage) (interface{}, error) {
+		<-release
+		return 1, nil
+	})
+
+	client := newMockClient(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := client.GetTimeContext(ctx)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestCallContext_Deadline(t *testing.T) {
+	s := newMockServer(t)
+	release := make(chan struct{})
+	defer close(release)
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		<-release
+		return map[string]interface{}{}, nil
+	})
+
+	client := newMockClient(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetOrderBookContext(ctx, &models.GetOrderBookParams{InstrumentName: "BTC-PERPETUAL"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestCallContext_Result(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/get_time", func(json.RawMessage) (interface{}, error) {
+		return 1700000000000, nil
+	})
+
+	client := newMockClient(t, s)
+
+	tm, err := client.GetTimeContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000000), tm)
+
+	tm, err = client.GetTime()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000000), tm)
+}
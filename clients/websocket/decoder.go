@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Decoder decodes the data of a notification of channel into the value
+// passed to the listeners and Subscriptions of the channel. Decoders run on
+// the read loop of the connection.
+type Decoder func(channel Channel, data json.RawMessage) (interface{}, error)
+
+// DecodeJSON returns a Decoder unmarshalling the data into a new *T
+func DecodeJSON[T any]() Decoder {
+	return func(_ Channel, data json.RawMessage) (interface{}, error) {
+		var notification T
+		if err := jsoniter.Unmarshal(data, &notification); err != nil {
+			return nil, err
+		}
+		return &notification, nil
+	}
+}
+
+// DecodeError is passed to the decode error handler and the Subscriptions of
+// the channel when a notification cannot be decoded
+type DecodeError struct {
+	Channel string
+	Data    json.RawMessage
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode channel %s: %v", e.Channel, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrorHandler is called with every notification that cannot be
+// decoded. It runs on the read loop of the connection and must not block.
+type DecodeErrorHandler func(err *DecodeError)
+
+// decoderRegistry holds the decoders of a client by channel kind
+type decoderRegistry struct {
+	mu       sync.RWMutex
+	decoders map[ChannelKind]Decoder
+	onError  DecodeErrorHandler
+}
+
+func newDecoderRegistry() *decoderRegistry {
+	return &decoderRegistry{
+		decoders: map[ChannelKind]Decoder{
+			ChannelKindAnnouncements:              DecodeJSON[models.AnnouncementsNotification](),
+			ChannelKindBlockTradeConfirmations:    DecodeJSON[models.BlockTradeConfirmationsNotification](),
+			ChannelKindBook:                       decodeBook,
+			ChannelKindChartTrades:                DecodeJSON[models.ChartTradesNotification](),
+			ChannelKindPriceIndex:                 DecodeJSON[models.DeribitPriceIndexNotification](),
+			ChannelKindPriceRanking:               DecodeJSON[models.DeribitPriceRankingNotification](),
+			ChannelKindVolatilityIndex:            DecodeJSON[models.DeribitVolatilityIndexNotification](),
+			ChannelKindEstimatedExpirationPrice:   DecodeJSON[models.EstimatedExpirationPriceNotification](),
+			ChannelKindIncrementalTicker:          DecodeJSON[models.IncrementalTickerNotification](),
+			ChannelKindInstrumentState:            DecodeJSON[models.InstrumentStateNotification](),
+			ChannelKindMarkPriceOptions:           DecodeJSON[models.MarkpriceOptionsNotification](),
+			ChannelKindPerpetual:                  DecodeJSON[models.PerpetualNotification](),
+			ChannelKindPlatformState:              DecodeJSON[models.PlatformStateNotification](),
+			ChannelKindPlatformStatePublicMethods: DecodeJSON[models.PlatformStateNotification](),
+			ChannelKindQuote:                      DecodeJSON[models.QuoteNotification](),
+			ChannelKindTicker:                     DecodeJSON[models.TickerNotification](),
+			ChannelKindTrades:                     DecodeJSON[models.TradesNotification](),
+			ChannelKindUserAccessLog:              DecodeJSON[models.UserAccessLogNotification](),
+			ChannelKindUserChanges:                DecodeJSON[models.UserChangesNotification](),
+			ChannelKindUserMMPTrigger:             DecodeJSON[models.UserMMPTriggerNotification](),
+			ChannelKindUserOrders:                 decodeUserOrders,
+			ChannelKindUserPortfolio:              DecodeJSON[models.PortfolioNotification](),
+			ChannelKindUserTrades:                 DecodeJSON[models.UserTradesNotification](),
+		},
+	}
+}
+
+// RegisterDecoder sets the decoder of the channels of kind, replacing the
+// built-in one. Kinds unknown to ParseChannel match channel names up to a
+// dot, e.g. kind "rfq" decodes "rfq.btc".
+func (c *DeribitWSClient) RegisterDecoder(kind ChannelKind, decoder Decoder) {
+	c.decoders.mu.Lock()
+	defer c.decoders.mu.Unlock()
+	c.decoders.decoders[kind] = decoder
+}
+
+// OnDecodeError sets the handler of notifications that cannot be decoded,
+// they are logged when no handler is set
+func (c *DeribitWSClient) OnDecodeError(handler DecodeErrorHandler) {
+	c.decoders.mu.Lock()
+	defer c.decoders.mu.Unlock()
+	c.decoders.onError = handler
+}
+
+// lookup returns the parsed channel and its decoder, notifications without
+// a decoder are delivered as json.RawMessage
+func (r *decoderRegistry) lookup(name string) (Channel, Decoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if channel, ok := parseChannel(name); ok {
+		decoder, ok := r.decoders[channel.Kind]
+		return channel, decoder, ok
+	}
+
+	// the longest registered prefix of a channel ParseChannel does not know
+	for prefix := name; prefix != ""; {
+		if decoder, ok := r.decoders[ChannelKind(prefix)]; ok {
+			return Channel{Kind: ChannelKind(prefix)}, decoder, true
+		}
+
+		i := strings.LastIndexByte(prefix, '.')
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+
+	return Channel{}, nil, false
+}
+
+func (r *decoderRegistry) errorHandler() DecodeErrorHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.onError
+}
+
+var (
+	decodeOrderBookGroup = DecodeJSON[models.OrderBookGroupNotification]()
+	decodeOrderBookRaw   = DecodeJSON[models.OrderBookRawNotification]()
+	decodeOrderBook      = DecodeJSON[models.OrderBookNotification]()
+
+	decodeOrderBookRawDecimal = DecodeJSON[models.OrderBookRawDecimalNotification]()
+	decodeOrderBookDecimal    = DecodeJSON[models.OrderBookDecimalNotification]()
+)
+
+// decodeBook decodes the raw, aggregated and grouped order book channels
+func decodeBook(channel Channel, data json.RawMessage) (interface{}, error) {
+	if channel.Grouped() {
+		// book.BTC-PERPETUAL.none.10.100ms
+		return decodeOrderBookGroup(channel, data)
+	}
+	if channel.Interval == IntervalRaw {
+		// book.BTC-PERPETUAL.raw
+		return decodeOrderBookRaw(channel, data)
+	}
+	// book.BTC-PERPETUAL.100ms
+	return decodeOrderBook(channel, data)
+}
+
+// decodeUserOrders decodes user.orders notifications, raw channels deliver a
+// single order instead of a list
+func decodeUserOrders(_ Channel, data json.RawMessage) (interface{}, error) {
+	var notification models.UserOrderNotification
+	if len(data) > 0 && data[0] == '{' {
+		var order websocketmodels.Order
+		if err := jsoniter.Unmarshal(data, &order); err != nil {
+			return nil, err
+		}
+		notification = append(notification, order)
+		return &notification, nil
+	}
+
+	if err := jsoniter.Unmarshal(data, &notification); err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
@@ -0,0 +1,127 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rfqNotification struct {
+	State  string `json:"state"`
+	Amount int    `json:"amount"`
+}
+
+func TestDecoder_Register(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	ctx := context.Background()
+
+	client.RegisterDecoder("rfq", DecodeJSON[rfqNotification]())
+
+	sub, err := Subscribe[*rfqNotification](ctx, client, "rfq.btc")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish("rfq.btc", map[string]interface{}{"state": "created", "amount": 5})
+
+	n := receive(t, sub.C())
+	assert.Equal(t, rfqNotification{State: "created", Amount: 5}, *n)
+}
+
+func TestDecoder_ReplaceBuiltin(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	var mu sync.Mutex
+	var decoded []Channel
+	client.RegisterDecoder(ChannelKindTicker, func(channel Channel, data json.RawMessage) (interface{}, error) {
+		mu.Lock()
+		decoded = append(decoded, channel)
+		mu.Unlock()
+		return string(data), nil
+	})
+
+	sub, err := Subscribe[string](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+
+	assert.JSONEq(t, `{"timestamp":1}`, receive(t, sub.C()))
+	mu.Lock()
+	assert.Equal(t, []Channel{TickerChannel("BTC-PERPETUAL", Interval100ms)}, decoded)
+	mu.Unlock()
+}
+
+func TestDecoder_UnknownChannelIsRaw(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	received := make(chan json.RawMessage, 1)
+	client.On("unknown.channel", func(data json.RawMessage) {
+		received <- data
+	})
+	_, err := client.Subscribe([]string{"unknown.channel"})
+	require.NoError(t, err)
+
+	s.Publish("unknown.channel", []int{1, 2})
+
+	assert.JSONEq(t, `[1,2]`, string(receive(t, received)))
+}
+
+func TestDecoder_ErrorHandler(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	failed := make(chan *DecodeError, 1)
+	client.OnDecodeError(func(err *DecodeError) {
+		failed <- err
+	})
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": "x"})
+
+	decodeErr := receive(t, failed)
+	assert.Equal(t, tickerChannel, decodeErr.Channel)
+	assert.JSONEq(t, `{"timestamp":"x"}`, string(decodeErr.Data))
+	assert.Error(t, decodeErr.Err)
+
+	streamErr := receive(t, sub.Err())
+	var target *DecodeError
+	assert.True(t, errors.As(streamErr, &target))
+
+	// the stream keeps delivering after a decode failure
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 3})
+	select {
+	case n := <-sub.C():
+		assert.Equal(t, int64(3), n.Timestamp)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestDecoderRegistry_Lookup(t *testing.T) {
+	r := newDecoderRegistry()
+	r.decoders["block_rfq.trades"] = DecodeJSON[rfqNotification]()
+
+	channel, _, ok := r.lookup("block_rfq.trades.btc")
+	assert.True(t, ok)
+	assert.Equal(t, ChannelKind("block_rfq.trades"), channel.Kind)
+
+	_, _, ok = r.lookup("block_rfq.maker.btc")
+	assert.False(t, ok)
+
+	// known kinds without a decoder are raw
+	_, _, ok = r.lookup("user.lock")
+	assert.False(t, ok)
+}
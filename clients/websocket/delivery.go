@@ -0,0 +1,357 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryPolicy selects what a delivery queue does with a notification when
+// it is full
+type DeliveryPolicy int
+
+const (
+	// DeliveryBlock waits for space in the queue, stalling the connection
+	// until the listeners of the channel catch up
+	DeliveryBlock DeliveryPolicy = iota
+	// DeliveryDropOldest drops the oldest queued notification
+	DeliveryDropOldest
+	// DeliveryCoalesce keeps only the latest notification not yet delivered,
+	// for channels where only the current state matters like tickers or
+	// grouped books
+	DeliveryCoalesce
+)
+
+func (p DeliveryPolicy) String() string {
+	switch p {
+	case DeliveryBlock:
+		return "block"
+	case DeliveryDropOldest:
+		return "drop_oldest"
+	case DeliveryCoalesce:
+		return "coalesce"
+	}
+	return "unknown"
+}
+
+const defaultDeliveryQueueSize = 1024
+
+// DeliveryOptions configure the delivery queue of a channel.
+//
+// With DeliveryBlock, the default, no notification is lost, but a channel
+// whose listeners fall behind stalls the read loop of the connection once its
+// queue is full: other channels and RPC responses wait as well. A
+// Subscription that is not read fills up the same way, its send blocks the
+// delivery of the channel. Use DeliveryDropOldest or DeliveryCoalesce for
+// channels whose listeners may lag.
+type DeliveryOptions struct {
+	Policy DeliveryPolicy
+	// Size is the number of queued notifications, defaults to 1024. A
+	// coalescing queue holds one notification.
+	Size int
+}
+
+// DeliveryStats are the counters of the delivery queue of a channel
+type DeliveryStats struct {
+	Delivered uint64
+	Dropped   uint64
+	Coalesced uint64
+	// Queued is the number of notifications waiting for delivery
+	Queued int
+}
+
+// SetDelivery sets the delivery options of channel, they take precedence over
+// the options set with SetDeliveryFor
+func (c *DeribitWSClient) SetDelivery(channel string, opts DeliveryOptions) {
+	c.deliveries.mu.Lock()
+	defer c.deliveries.mu.Unlock()
+
+	c.deliveries.channels[channel] = opts
+	if q, ok := c.deliveries.queues[channel]; ok {
+		q.setOptions(opts)
+	}
+}
+
+// SetDeliveryFor sets the delivery options of the channels matched by
+// matcher, e.g. SetDeliveryFor(ChannelKindTicker, DeliveryOptions{Policy:
+// DeliveryCoalesce}). The first matcher set for a channel applies.
+func (c *DeribitWSClient) SetDeliveryFor(matcher ChannelMatcher, opts DeliveryOptions) {
+	c.deliveries.mu.Lock()
+	defer c.deliveries.mu.Unlock()
+
+	c.deliveries.matchers = append(c.deliveries.matchers, deliveryMatcher{matcher: matcher, opts: opts})
+	for channel, q := range c.deliveries.queues {
+		if _, ok := c.deliveries.channels[channel]; !ok {
+			q.setOptions(c.deliveries.optionsLocked(channel))
+		}
+	}
+}
+
+// DeliveryStats returns the counters of the delivery queue of channel, zero
+// before its first notification
+func (c *DeribitWSClient) DeliveryStats(channel string) DeliveryStats {
+	c.deliveries.mu.Lock()
+	q, ok := c.deliveries.queues[channel]
+	c.deliveries.mu.Unlock()
+
+	if !ok {
+		return DeliveryStats{}
+	}
+	return q.stats()
+}
+
+// deliveryRegistry holds a delivery queue per channel. Every queue delivers
+// its notifications in order on its own goroutine, so a slow listener stalls
+// only its channel. The read loop of the connection waits only for a full
+// DeliveryBlock queue, see DeliveryOptions.
+type deliveryRegistry struct {
+	mu       sync.Mutex
+	queues   map[string]*deliveryQueue
+	channels map[string]DeliveryOptions
+	matchers []deliveryMatcher
+}
+
+type deliveryMatcher struct {
+	matcher ChannelMatcher
+	opts    DeliveryOptions
+}
+
+func newDeliveryRegistry() *deliveryRegistry {
+	return &deliveryRegistry{
+		queues:   make(map[string]*deliveryQueue),
+		channels: make(map[string]DeliveryOptions),
+	}
+}
+
+func (r *deliveryRegistry) optionsLocked(channel string) DeliveryOptions {
+	if opts, ok := r.channels[channel]; ok {
+		return opts
+	}
+	for _, m := range r.matchers {
+		if m.matcher.Match(channel) {
+			return m.opts
+		}
+	}
+	return DeliveryOptions{}
+}
+
+// enqueue queues the notification of channel, starting the queue of the
+// channel on its first notification. A queue replacing a released one still
+// draining starts delivering once the released one is done, so notifications
+// of a channel are never delivered concurrently.
+func (c *DeribitWSClient) enqueue(channel string, notification interface{}, data json.RawMessage) {
+	c.deliveries.mu.Lock()
+	q, ok := c.deliveries.queues[channel]
+	if !ok || q.isClosed() {
+		prev := q
+		q = newDeliveryQueue(c.deliveries.optionsLocked(channel))
+		q.prev = prev
+		if !c.spawn(func() { c.runDelivery(channel, q) }) {
+			c.deliveries.mu.Unlock()
+			return
+		}
+		c.deliveries.queues[channel] = q
+	}
+	c.deliveries.mu.Unlock()
+
+	q.push(queuedNotification{notification: notification, data: data, received: time.Now()}, c.stopCtx.Done())
+}
+
+// queuedNotification is a notification waiting in a delivery queue
+type queuedNotification struct {
+	notification interface{}
+	data         json.RawMessage
+	received     time.Time
+}
+
+// release stops the queues of channels once they are drained, used after
+// unsubscribing. A queue stays registered until it is drained.
+func (r *deliveryRegistry) release(channels []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, channel := range channels {
+		if q, ok := r.queues[channel]; ok {
+			q.close()
+		}
+	}
+}
+
+// remove drops the drained queue q of channel unless it was replaced
+func (r *deliveryRegistry) remove(channel string, q *deliveryQueue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.queues[channel] == q {
+		delete(r.queues, channel)
+	}
+}
+
+func (c *DeribitWSClient) runDelivery(channel string, q *deliveryQueue) {
+	defer close(q.done)
+	defer c.deliveries.remove(channel, q)
+
+	if q.prev != nil {
+		select {
+		case <-q.prev.done:
+		case <-c.stopCtx.Done():
+			return
+		}
+		q.prev = nil
+	}
+
+	for {
+		v, ok := q.pop(c.stopCtx.Done())
+		if !ok {
+			return
+		}
+		queued := v.(queuedNotification)
+		c.dispatch(channel, queued.notification, queued.data, queued.received)
+		q.delivered.Add(1)
+	}
+}
+
+// deliveryQueue is a bounded FIFO of notifications applying a DeliveryPolicy
+// when full
+type deliveryQueue struct {
+	mu     sync.Mutex
+	opts   DeliveryOptions
+	items  []interface{}
+	closed bool
+	// notEmpty and notFull wake pop and a blocked push
+	notEmpty chan struct{}
+	notFull  chan struct{}
+	// done is closed once the queue is drained after close, prev is the
+	// released queue of the channel delivering before this one
+	done chan struct{}
+	prev *deliveryQueue
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+func newDeliveryQueue(opts DeliveryOptions) *deliveryQueue {
+	q := &deliveryQueue{
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	q.setOptions(opts)
+	return q
+}
+
+func (q *deliveryQueue) setOptions(opts DeliveryOptions) {
+	if opts.Size <= 0 {
+		opts.Size = defaultDeliveryQueueSize
+	}
+	if opts.Policy == DeliveryCoalesce {
+		opts.Size = 1
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.opts = opts
+}
+
+// push queues v, with DeliveryBlock it waits for space until stop is closed
+func (q *deliveryQueue) push(v interface{}, stop <-chan struct{}) {
+	q.mu.Lock()
+	for !q.closed && len(q.items) >= q.opts.Size {
+		switch q.opts.Policy {
+		case DeliveryDropOldest:
+			q.items[0] = nil
+			q.items = q.items[1:]
+			q.dropped.Add(1)
+			continue
+		case DeliveryCoalesce:
+			q.items[len(q.items)-1] = v
+			q.coalesced.Add(1)
+			q.mu.Unlock()
+			return
+		}
+
+		q.mu.Unlock()
+		select {
+		case <-q.notFull:
+		case <-stop:
+			return
+		}
+		q.mu.Lock()
+	}
+
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.items = append(q.items, v)
+	q.mu.Unlock()
+
+	signal(q.notEmpty)
+}
+
+// pop returns the oldest notification, waiting for one until stop is closed.
+// It returns false once stop is closed or the queue is closed and drained.
+func (q *deliveryQueue) pop(stop <-chan struct{}) (interface{}, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			v := q.items[0]
+			q.items[0] = nil
+			q.items = q.items[1:]
+			q.mu.Unlock()
+
+			signal(q.notFull)
+			return v, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+
+		select {
+		case <-q.notEmpty:
+		case <-stop:
+			return nil, false
+		}
+	}
+}
+
+func (q *deliveryQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	signal(q.notEmpty)
+	signal(q.notFull)
+}
+
+func (q *deliveryQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+func (q *deliveryQueue) stats() DeliveryStats {
+	q.mu.Lock()
+	queued := len(q.items)
+	q.mu.Unlock()
+
+	return DeliveryStats{
+		Delivered: q.delivered.Load(),
+		Dropped:   q.dropped.Load(),
+		Coalesced: q.coalesced.Load(),
+		Queued:    queued,
+	}
+}
+
+// signal wakes the waiter of ch without blocking
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryQueue_DropOldest(t *testing.T) {
+	q := newDeliveryQueue(DeliveryOptions{Policy: DeliveryDropOldest, Size: 2})
+	stop := make(chan struct{})
+
+	for i := 1; i <= 5; i++ {
+		q.push(i, stop)
+	}
+
+	assert.Equal(t, DeliveryStats{Dropped: 3, Queued: 2}, q.stats())
+	v, _ := q.pop(stop)
+	assert.Equal(t, 4, v)
+	v, _ = q.pop(stop)
+	assert.Equal(t, 5, v)
+}
+
+func TestDeliveryQueue_Coalesce(t *testing.T) {
+	q := newDeliveryQueue(DeliveryOptions{Policy: DeliveryCoalesce, Size: 10})
+	stop := make(chan struct{})
+
+	for i := 1; i <= 5; i++ {
+		q.push(i, stop)
+	}
+
+	assert.Equal(t, DeliveryStats{Coalesced: 4, Queued: 1}, q.stats())
+	v, _ := q.pop(stop)
+	assert.Equal(t, 5, v)
+}
+
+func TestDeliveryQueue_Block(t *testing.T) {
+	q := newDeliveryQueue(DeliveryOptions{Policy: DeliveryBlock, Size: 1})
+	stop := make(chan struct{})
+
+	q.push(1, stop)
+
+	pushed := make(chan struct{})
+	go func() {
+		q.push(2, stop)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push did not block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	v, _ := q.pop(stop)
+	assert.Equal(t, 1, v)
+	select {
+	case <-pushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("push still blocked")
+	}
+	v, _ = q.pop(stop)
+	assert.Equal(t, 2, v)
+
+	q.close()
+	_, ok := q.pop(stop)
+	assert.False(t, ok)
+}
+
+func TestDelivery_SlowListener(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	const slow = "ticker.ETH-PERPETUAL.100ms"
+
+	gate := make(chan struct{})
+	var order []int64
+	client.On(slow, func(n *models.TickerNotification) {
+		<-gate
+		order = append(order, n.Timestamp)
+	})
+	fast := make(chan *models.TickerNotification, 1)
+	client.On(tickerChannel, func(n *models.TickerNotification) {
+		fast <- n
+	})
+
+	_, err := client.Subscribe([]string{slow, tickerChannel})
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		s.Publish(slow, map[string]interface{}{"timestamp": i})
+	}
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 10})
+
+	// neither other channels nor responses wait for the stalled listener
+	assert.Equal(t, int64(10), receive(t, fast).Timestamp)
+	_, err = client.GetTimeContext(context.Background())
+	require.NoError(t, err)
+
+	close(gate)
+	require.Eventually(t, func() bool {
+		return client.DeliveryStats(slow).Delivered == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{1, 2, 3}, order)
+}
+
+func TestDelivery_CoalesceByKind(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	client.SetDeliveryFor(ChannelKindTicker, DeliveryOptions{Policy: DeliveryCoalesce})
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// nobody reads yet, the delivery stalls once the subscription buffer is full
+	for i := 0; i < defaultStreamBuffer+1; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+	for i := 1000; i < 1010; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+
+	// more notifications than fit into the subscription buffer and the queue,
+	// all but the one possibly blocked in delivery are delivered, coalesced or
+	// queued
+	require.Eventually(t, func() bool {
+		stats := client.DeliveryStats(tickerChannel)
+		return stats.Delivered+stats.Coalesced+uint64(stats.Queued) >= defaultStreamBuffer+10
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.NotZero(t, client.DeliveryStats(tickerChannel).Coalesced)
+
+	var last int64
+	for last != 1009 {
+		last = receive(t, sub.C()).Timestamp
+	}
+	require.Eventually(t, func() bool {
+		stats := client.DeliveryStats(tickerChannel)
+		return stats.Delivered+stats.Coalesced == defaultStreamBuffer+1+10
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, client.DeliveryStats(tickerChannel).Dropped)
+}
+
+func TestDelivery_ResubscribeKeepsOrder(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var order []int64
+	var running, overlapped atomic.Int32
+	client.On(tickerChannel, func(n *models.TickerNotification) {
+		if running.Add(1) > 1 {
+			overlapped.Add(1)
+		}
+		defer running.Add(-1)
+		<-gate
+
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, n.Timestamp)
+	})
+
+	_, err := client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+	require.Eventually(t, func() bool {
+		return client.DeliveryStats(tickerChannel).Queued == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// the released queue is still draining when the channel comes back
+	_, err = client.Unsubscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	_, err = client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	for i := 4; i <= 6; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+	require.Eventually(t, func() bool {
+		return client.DeliveryStats(tickerChannel).Queued == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	close(gate)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 6
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, order)
+	assert.Zero(t, overlapped.Load())
+}
+
+func TestDelivery_BlockStallsReadLoop(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	client.SetDelivery(tickerChannel, DeliveryOptions{Policy: DeliveryBlock, Size: 1})
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// the unread Subscription fills, then the queue, then the read loop waits
+	total := defaultStreamBuffer + 3
+	for i := 0; i < total; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+	require.Eventually(t, func() bool {
+		return client.DeliveryStats(tickerChannel).Queued == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = client.GetTimeContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// reading the Subscription releases the connection
+	for i := 0; i < total; i++ {
+		assert.Equal(t, int64(i), receive(t, sub.C()).Timestamp)
+	}
+	_, err = client.GetTimeContext(context.Background())
+	assert.NoError(t, err)
+}
@@ -1,12 +1,87 @@
 package websocket
 
 import (
+	"encoding/json"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
 	"github.com/chuckpreslar/emission"
 )
 
-// On adds a listener to a specific event
-func (c *DeribitWSClient) On(event interface{}, listener interface{}) *emission.Emitter {
-	return c.emitter.On(event, listener)
+// Event names the events emitted by the client itself, next to the
+// subscription channels. Listeners must be registered with these typed
+// constants, plain strings are reserved for channels.
+type Event string
+
+const (
+	// EventHeartbeat is emitted with a *websocketmodels.Heartbeat for every heartbeat message
+	EventHeartbeat Event = "heartbeat"
+	// EventHeartbeatMissed is emitted with the time.Time of the last heartbeat
+	// before a silent connection is closed
+	EventHeartbeatMissed Event = "heartbeat_missed"
+)
+
+// EventSubscribeProgress is emitted with a *websocketmodels.SubscribeProgress
+// after every subscribe request
+const EventSubscribeProgress Event = "subscribe_progress"
+
+// EventOrderBookGap is emitted with a *websocketmodels.OrderBookGap when an
+// OrderBook detects a gap or a crossed book and resyncs
+const EventOrderBookGap Event = "order_book_gap"
+
+// Connection lifecycle events, emitted with a *websocketmodels.ConnectionEvent
+const (
+	EventConnecting     Event = "connecting"
+	EventConnected      Event = "connected"
+	EventAuthenticated  Event = "authenticated"
+	EventTokenRefreshed Event = "token_refreshed"
+	EventLoggedOut      Event = "logged_out"
+	EventDisconnected   Event = "disconnected"
+	EventReconnecting   Event = "reconnecting"
+	EventGaveUp         Event = "gave_up"
+)
+
+// emitConnectionEvent emits a lifecycle event stamped with the current time
+func (c *DeribitWSClient) emitConnectionEvent(event Event, e websocketmodels.ConnectionEvent) {
+	e.Time = time.Now()
+	c.Emit(event, &e)
+}
+
+// publish queues the notification of channel for delivery, see SetDelivery.
+// data is the JSON the notification was decoded from, nil if it was not.
+func (c *DeribitWSClient) publish(channel string, notification interface{}, data json.RawMessage) {
+	c.enqueue(channel, notification, data)
+}
+
+// dispatch caches the notification of channel and passes it to the
+// listeners added with On, including those of matching ChannelMatchers, and
+// to the Subscriptions and Listeners of the channel. Notifications of a Pool
+// member are passed on to the Pool.
+func (c *DeribitWSClient) dispatch(channel string, notification interface{}, data json.RawMessage, received time.Time) {
+	var sinks []streamSink
+	c.cache.store(channel, notification, received, func() {
+		sinks = c.streams.sinks(channel)
+	})
+
+	c.matchers.emit(c.emitter, channel, notification)
+	deliverSinks(sinks, channel, notification, data)
+	if c.forward != nil {
+		c.forward(channel, notification, data, received)
+	}
+}
+
+// On adds a listener to a specific event. Listeners of a ChannelMatcher are
+// called with the channel name and the notification, e.g.
+//
+//	client.On(websocket.ChannelPattern("ticker.*"), func(channel string, n *models.TickerNotification) {})
+//
+// Cached notifications of the channel, see EnableCache, are passed to the
+// listener before On returns.
+func (c *DeribitWSClient) On(event interface{}, listener interface{}) (emitter *emission.Emitter) {
+	c.cache.withReplay(event, func() {
+		emitter = c.matchers.on(c.emitter, event, listener)
+	}, replayListener(event, listener))
+	return
 }
 
 // Emit emits an event
@@ -16,5 +91,5 @@ func (c *DeribitWSClient) Emit(event interface{}, arguments ...interface{}) *emi
 
 // Off removes a listener for an event
 func (c *DeribitWSClient) Off(event interface{}, listener interface{}) *emission.Emitter {
-	return c.emitter.Off(event, listener)
+	return c.matchers.off(c.emitter, event, listener)
 }
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frameRecorder is a FrameInterceptor keeping a copy of every frame
+type frameRecorder struct {
+	mu     sync.Mutex
+	frames []websocketmodels.Frame
+}
+
+func (r *frameRecorder) intercept(next websocketmodels.FrameHandler) websocketmodels.FrameHandler {
+	return func(frame *websocketmodels.Frame) error {
+		err := next(frame)
+		if err == nil {
+			r.mu.Lock()
+			r.frames = append(r.frames, *frame)
+			r.mu.Unlock()
+		}
+		return err
+	}
+}
+
+func (r *frameRecorder) find(direction websocketmodels.FrameDirection, match func(websocketmodels.Frame) bool) (websocketmodels.Frame, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.frames {
+		if f.Direction == direction && match(f) {
+			return f, true
+		}
+	}
+	return websocketmodels.Frame{}, false
+}
+
+func withFrameInterceptors(interceptors ...websocketmodels.FrameInterceptor) func(*deribit.Configuration) {
+	return func(cfg *deribit.Configuration) {
+		cfg.FrameInterceptors = interceptors
+	}
+}
+
+func TestFrameInterceptors_Correlation(t *testing.T) {
+	s := newMockServer(t)
+	recorder := &frameRecorder{}
+	client := newMockClient(t, s, withFrameInterceptors(recorder.intercept))
+
+	_, err := client.GetTime()
+	require.NoError(t, err)
+
+	request, ok := recorder.find(websocketmodels.FrameOutbound, func(f websocketmodels.Frame) bool {
+		return f.Method == "public/get_time"
+	})
+	require.True(t, ok)
+	require.NotNil(t, request.ID)
+	assert.Contains(t, string(request.Data), `"public/get_time"`)
+	assert.False(t, request.Time.IsZero())
+
+	response, ok := recorder.find(websocketmodels.FrameInbound, func(f websocketmodels.Frame) bool {
+		return f.ID != nil && *f.ID == *request.ID
+	})
+	require.True(t, ok)
+	assert.Empty(t, response.Method)
+	assert.Contains(t, string(response.Data), `"result"`)
+	assert.False(t, response.Time.Before(request.Time))
+}
+
+func TestFrameInterceptors_Order(t *testing.T) {
+	s := newMockServer(t)
+
+	var mu sync.Mutex
+	var calls []string
+	named := func(name string) websocketmodels.FrameInterceptor {
+		return func(next websocketmodels.FrameHandler) websocketmodels.FrameHandler {
+			return func(frame *websocketmodels.Frame) error {
+				if frame.Direction == websocketmodels.FrameOutbound && frame.Method == "public/get_time" {
+					mu.Lock()
+					calls = append(calls, name)
+					mu.Unlock()
+				}
+				return next(frame)
+			}
+		}
+	}
+	client := newMockClient(t, s, withFrameInterceptors(named("outer"), named("inner")))
+
+	_, err := client.GetTime()
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"outer", "inner"}, calls)
+}
+
+func TestFrameInterceptors_FaultInjection(t *testing.T) {
+	s := newMockServer(t)
+	injected := errors.New("injected")
+	client := newMockClient(t, s, withFrameInterceptors(func(next websocketmodels.FrameHandler) websocketmodels.FrameHandler {
+		return func(frame *websocketmodels.Frame) error {
+			if frame.Method == "public/get_time" {
+				return injected
+			}
+			return next(frame)
+		}
+	}))
+
+	_, err := client.GetTime()
+	assert.ErrorIs(t, err, injected)
+	assert.Zero(t, s.Calls("public/get_time"))
+
+	_, err = client.Test()
+	assert.NoError(t, err)
+}
+
+func TestFrameInterceptors_RewriteInbound(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, withFrameInterceptors(func(next websocketmodels.FrameHandler) websocketmodels.FrameHandler {
+		return func(frame *websocketmodels.Frame) error {
+			if err := next(frame); err != nil {
+				return err
+			}
+			if frame.Method == "subscription" {
+				frame.Data = bytes.ReplaceAll(frame.Data, []byte(`"timestamp":1`), []byte(`"timestamp":2`))
+			}
+			return nil
+		}
+	}))
+
+	received := make(chan int64, 1)
+	client.On(tickerChannel, func(n *models.TickerNotification) {
+		received <- n.Timestamp
+	})
+	_, err := client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+	assert.Equal(t, int64(2), receive(t, received))
+}
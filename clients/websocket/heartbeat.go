@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/coder/websocket"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+const (
+	HeartbeatTypeHeartbeat   = "heartbeat"
+	HeartbeatTypeTestRequest = "test_request"
+)
+
+const defaultMaxMissedHeartbeats = 2
+
+// heartbeatProcess answers test_request messages with public/test and
+// records the time of the last heartbeat seen on the connection
+func (c *DeribitWSClient) heartbeatProcess(ctx context.Context, req *jsonrpc2.Request) {
+	c.lastHeartbeat.Store(time.Now().UnixNano())
+
+	var heartbeat websocketmodels.Heartbeat
+	if req.Params != nil {
+		if err := json.Unmarshal(*req.Params, &heartbeat); err != nil {
+			c.logger.WithContext(ctx).Warnln("websocket unmarshal heartbeat fail", err)
+			return
+		}
+	}
+
+	if heartbeat.Type == HeartbeatTypeTestRequest {
+		// Handle runs on the read loop of the jsonrpc2 conn, so the reply
+		// must not wait for its own response here
+		c.spawn(func() {
+			if _, err := c.TestContext(ctx); err != nil {
+				c.logger.WithContext(ctx).Warnln("heartbeat test_request reply fail", err)
+			}
+		})
+	}
+
+	c.Emit(EventHeartbeat, &heartbeat)
+}
+
+// monitorHeartbeat closes conn once no heartbeat has been received for more
+// than MaxMissedHeartbeats intervals, which lets the reconnect routine take over
+func (c *DeribitWSClient) monitorHeartbeat(ctx context.Context, conn *websocket.Conn, rpcConn *jsonrpc2.Conn) {
+	if c.cfg.HeartBeatInterval <= 0 {
+		return
+	}
+
+	interval := time.Duration(c.cfg.HeartBeatInterval * float64(time.Second))
+
+	missed := c.cfg.MaxMissedHeartbeats
+	if missed <= 0 {
+		missed = defaultMaxMissedHeartbeats
+	}
+	deadline := time.Duration(missed+1) * interval
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			last := time.Unix(0, c.lastHeartbeat.Load())
+			if time.Since(last) <= deadline {
+				continue
+			}
+
+			c.logger.WithContext(ctx).Warnf("no heartbeat since %s, closing connection\n", last)
+			c.Emit(EventHeartbeatMissed, last)
+
+			_ = conn.CloseNow()
+			return
+		case <-rpcConn.DisconnectNotify():
+			c.logger.WithContext(ctx).Debugln("heartbeat stop")
+			return
+		case <-ctx.Done():
+			c.logger.WithContext(ctx).Debugln("heartbeat ctx done")
+			return
+		case <-c.stopCtx.Done():
+			c.logger.WithContext(ctx).Debugln("heartbeat stop")
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat_TestRequest(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	events := make(chan *websocketmodels.Heartbeat, 2)
+	client.On(EventHeartbeat, func(hb *websocketmodels.Heartbeat) {
+		events <- hb
+	})
+
+	s.Notify("heartbeat", map[string]string{"type": HeartbeatTypeTestRequest})
+
+	select {
+	case hb := <-events:
+		assert.Equal(t, HeartbeatTypeTestRequest, hb.Type)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no heartbeat event")
+	}
+
+	assert.Eventually(t, func() bool {
+		return s.Calls("public/test") == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHeartbeat_MissedTriggersReconnect(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.AutoReconnect = true
+		cfg.HeartBeatInterval = 0.05
+		cfg.MaxMissedHeartbeats = 1
+	})
+
+	missed := make(chan time.Time, 1)
+	client.On(EventHeartbeatMissed, func(last time.Time) {
+		select {
+		case missed <- last:
+		default:
+		}
+	})
+
+	select {
+	case <-missed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("missed heartbeat not detected")
+	}
+
+	assert.Eventually(t, func() bool {
+		return s.Accepted() >= 2 && client.IsConnected()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHeartbeat_KeepsConnectionAlive(t *testing.T) {
+	s := newMockServer(t)
+	newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.HeartBeatInterval = 0.05
+		cfg.MaxMissedHeartbeats = 2
+	})
+
+	for i := 0; i < 10; i++ {
+		s.Notify("heartbeat", map[string]string{"type": HeartbeatTypeHeartbeat})
+		time.Sleep(25 * time.Millisecond)
+	}
+
+	assert.Equal(t, 1, s.Accepted())
+}
@@ -1,6 +1,8 @@
 package websocket
 
 import (
+	"context"
+
 	models2 "github.com/BestNathan/deribit-api/clients/websocket/models"
 	"github.com/BestNathan/deribit-api/pkg/models"
 )
@@ -29,4 +31,38 @@ type TradingBehavior interface {
 	CancellByLabel(*models.CancelByLabelParams) (int, error)
 }
 
-var _ Behavior = (*DeribitWSClient)(nil)
+// ContextBehavior is Behavior with the methods taking a context
+type ContextBehavior interface {
+	AccountContextBehavior
+	MarketContextBehavior
+	TradingContextBehavior
+}
+
+type MarketContextBehavior interface {
+	MarketBehavior
+
+	GetOrderBookContext(context.Context, *models.GetOrderBookParams) (models.GetOrderBookResponse, error)
+
+	GetMarkPriceHistoryContext(context.Context, *models.GetMarkPriceHistoryParams) (models.MarkPriceHistory, error)
+}
+
+type AccountContextBehavior interface {
+	AccountBehavior
+
+	GetPositionsContext(context.Context, *models.GetPositionsParams) ([]models.Position, error)
+}
+
+type TradingContextBehavior interface {
+	TradingBehavior
+
+	BuyContext(context.Context, *models.BuyParams) (models.BuyResponse, error)
+	SellContext(context.Context, *models.SellParams) (models.SellResponse, error)
+
+	GetOrderHistoryByInstrumentContext(context.Context, *models.GetOrderHistoryByInstrumentParams) ([]models2.Order, error)
+	CancellByLabelContext(context.Context, *models.CancelByLabelParams) (int, error)
+}
+
+var (
+	_ Behavior        = (*DeribitWSClient)(nil)
+	_ ContextBehavior = (*DeribitWSClient)(nil)
+)
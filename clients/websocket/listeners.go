@@ -0,0 +1,193 @@
+package websocket
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/chuckpreslar/emission"
+)
+
+// ChannelMatcher selects channels by name. ChannelPattern and ChannelKind are
+// matchers: listeners added to them with On are called as
+// func(channel string, notification T) for every matching notification, and
+// Listen streams the matching notifications. Matchers are used as map keys
+// and must be comparable.
+type ChannelMatcher interface {
+	Match(channel string) bool
+}
+
+// ChannelPattern matches channel names with the syntax of path.Match, where
+// * matches any sequence of characters including dots, e.g. "ticker.*" or
+// "book.BTC-*.raw"
+type ChannelPattern string
+
+// Match reports whether channel matches the pattern, malformed patterns match
+// nothing
+func (p ChannelPattern) Match(channel string) bool {
+	ok, err := path.Match(string(p), channel)
+	return ok && err == nil
+}
+
+// Validate returns path.ErrBadPattern if the pattern is malformed
+func (p ChannelPattern) Validate() error {
+	if _, err := path.Match(string(p), ""); err != nil {
+		return fmt.Errorf("channel pattern %q: %w", string(p), err)
+	}
+	return nil
+}
+
+// Match reports whether channel is of kind k. Channels unknown to ParseChannel
+// match kinds up to a dot like decoders do, e.g. kind "rfq" matches "rfq.btc".
+func (k ChannelKind) Match(channel string) bool {
+	if !strings.HasPrefix(channel, string(k)) {
+		return false
+	}
+	if parsed, ok := parseChannel(channel); ok {
+		return parsed.Kind == k
+	}
+	return len(channel) == len(k) || channel[len(k)] == '.'
+}
+
+// matcherSet holds the matchers with listeners added by On, every
+// notification is emitted to the matchers it matches
+type matcherSet struct {
+	mu       sync.RWMutex
+	matchers map[ChannelMatcher]struct{}
+}
+
+func newMatcherSet() *matcherSet {
+	return &matcherSet{matchers: make(map[ChannelMatcher]struct{})}
+}
+
+// on adds listener to emitter and records event if it is a ChannelMatcher
+func (s *matcherSet) on(emitter *emission.Emitter, event, listener interface{}) *emission.Emitter {
+	if matcher, ok := event.(ChannelMatcher); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.matchers[matcher] = struct{}{}
+	}
+	return emitter.On(event, listener)
+}
+
+// off removes listener from emitter and forgets a ChannelMatcher event once
+// it has no listeners left
+func (s *matcherSet) off(emitter *emission.Emitter, event, listener interface{}) *emission.Emitter {
+	matcher, ok := event.(ChannelMatcher)
+	if !ok {
+		return emitter.Off(event, listener)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emitter = emitter.Off(event, listener)
+	if emitter.GetListenerCount(event) == 0 {
+		delete(s.matchers, matcher)
+	}
+	return emitter
+}
+
+// emit emits the notification of channel to the listeners of channel and of
+// its matchers
+func (s *matcherSet) emit(emitter *emission.Emitter, channel string, notification interface{}) {
+	emitter.Emit(channel, notification)
+	for _, matcher := range s.match(channel) {
+		emitter.Emit(matcher, channel, notification)
+	}
+}
+
+func (s *matcherSet) match(channel string) []ChannelMatcher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []ChannelMatcher
+	for m := range s.matchers {
+		if m.Match(channel) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
+
+// Notification is a notification of a Listener with the channel it was
+// received on
+type Notification[T any] struct {
+	Channel string
+	Data    T
+}
+
+// Listener is a typed stream of the notifications of every channel matched by
+// a ChannelMatcher, returned by Listen. It does not subscribe any channel,
+// the matching channels are subscribed with Subscribe or
+// DeribitWSClient.Subscribe.
+type Listener[T any] struct {
+	*stream[Notification[T]]
+
+	client  Subscriber
+	matcher ChannelMatcher
+	id      uint64
+	once    sync.Once
+}
+
+// Listen returns a Listener delivering the notifications of the channels
+// matched by matcher as T, e.g.
+//
+//	tickers, err := Listen[*models.TickerNotification](client, ChannelKindTicker)
+//	books, err := Listen[*models.OrderBookRawNotification](client, ChannelPattern("book.BTC-*.raw"))
+//
+// A notification that is neither a T nor a pointer to T is reported on Err.
+// Cached notifications of matching channels are delivered first.
+func Listen[T any](c Subscriber, matcher ChannelMatcher) (*Listener[T], error) {
+	if p, ok := matcher.(ChannelPattern); ok {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	l := &Listener[T]{
+		stream:  newStream[Notification[T]](),
+		client:  c,
+		matcher: matcher,
+	}
+
+	var err error
+	c.notificationCache().withReplay(matcher, func() {
+		err = c.streamRegistry().addMatcher(c, matcher, l)
+	}, func(channel string, notification interface{}) {
+		if err == nil {
+			l.deliver(channel, notification)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Matcher returns the matcher of the Listener
+func (l *Listener[T]) Matcher() ChannelMatcher {
+	return l.matcher
+}
+
+// Close stops the delivery and closes C and Err, the matched channels stay
+// subscribed
+func (l *Listener[T]) Close() {
+	l.once.Do(func() {
+		l.client.streamRegistry().removeMatcher(l.id)
+		l.closeLocal()
+	})
+}
+
+func (l *Listener[T]) setID(id uint64) {
+	l.id = id
+}
+
+func (l *Listener[T]) deliver(channel string, v interface{}) {
+	value, err := convertNotification[T](channel, v)
+	if err != nil {
+		l.fail(err)
+		return
+	}
+	l.send(Notification[T]{Channel: channel, Data: value})
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern ChannelPattern
+		channel string
+		want    bool
+	}{
+		{"ticker.*", "ticker.BTC-PERPETUAL.100ms", true},
+		{"ticker.*", "incremental_ticker.BTC-PERPETUAL", false},
+		{"book.BTC-*.raw", "book.BTC-PERPETUAL.raw", true},
+		{"book.BTC-*.raw", "book.BTC-PERPETUAL.100ms", false},
+		{"book.BTC-*.raw", "book.ETH-PERPETUAL.raw", false},
+		{"user.orders.*", "user.orders.BTC-PERPETUAL.raw", true},
+		{"book.[", "book.[", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.pattern.Match(tt.channel), "%s %s", tt.pattern, tt.channel)
+	}
+
+	assert.ErrorIs(t, ChannelPattern("book.[").Validate(), path.ErrBadPattern)
+	assert.NoError(t, ChannelPattern("book.*").Validate())
+}
+
+func TestChannelKind_Match(t *testing.T) {
+	assert.True(t, ChannelKindTicker.Match("ticker.BTC-PERPETUAL.100ms"))
+	assert.False(t, ChannelKindTicker.Match("trades.BTC-PERPETUAL.100ms"))
+	assert.True(t, ChannelKindPlatformState.Match("platform_state"))
+	assert.False(t, ChannelKindPlatformState.Match("platform_state.public_methods_state"))
+	assert.True(t, ChannelKind("rfq").Match("rfq.btc"))
+	assert.False(t, ChannelKind("rfq").Match("rfqs.btc"))
+}
+
+func TestOn_Matcher(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	var mu sync.Mutex
+	var byPattern, byKind []string
+	patternListener := func(channel string, n *models.TickerNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		byPattern = append(byPattern, channel)
+	}
+	client.On(ChannelPattern("ticker.BTC-*"), patternListener)
+
+	received := make(chan string, 4)
+	client.On(ChannelKindTicker, func(channel string, n *models.TickerNotification) {
+		mu.Lock()
+		byKind = append(byKind, channel)
+		mu.Unlock()
+		received <- channel
+	})
+
+	channels := []string{"ticker.BTC-PERPETUAL.100ms", "ticker.ETH-PERPETUAL.100ms"}
+	_, err := client.Subscribe(channels)
+	require.NoError(t, err)
+
+	for _, channel := range channels {
+		s.Publish(channel, map[string]interface{}{"timestamp": 1})
+		receive(t, received)
+	}
+
+	mu.Lock()
+	sort.Strings(byKind)
+	assert.Equal(t, channels, byKind)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, byPattern)
+	mu.Unlock()
+
+	client.Off(ChannelPattern("ticker.BTC-*"), patternListener)
+	assert.Equal(t, []ChannelMatcher{ChannelKindTicker}, client.matchers.match("ticker.BTC-PERPETUAL.100ms"))
+}
+
+func TestListen(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	ctx := context.Background()
+
+	tickers, err := Listen[*models.TickerNotification](client, ChannelKindTicker)
+	require.NoError(t, err)
+	defer tickers.Close()
+
+	_, err = client.SubscribeContext(ctx, []string{"ticker.BTC-PERPETUAL.100ms", "trades.BTC-PERPETUAL.100ms"})
+	require.NoError(t, err)
+
+	s.Publish("trades.BTC-PERPETUAL.100ms", []interface{}{})
+	s.Publish("ticker.BTC-PERPETUAL.100ms", map[string]interface{}{"timestamp": 5})
+
+	n := receive(t, tickers.C())
+	assert.Equal(t, "ticker.BTC-PERPETUAL.100ms", n.Channel)
+	assert.Equal(t, int64(5), n.Data.Timestamp)
+	assert.Equal(t, ChannelMatcher(ChannelKindTicker), tickers.Matcher())
+
+	tickers.Close()
+	_, ok := <-tickers.C()
+	assert.False(t, ok)
+	assert.Empty(t, client.streams.sinks("ticker.BTC-PERPETUAL.100ms"))
+	// the channels stay subscribed
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+}
+
+func TestListen_UnexpectedType(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	l, err := Listen[*models.TradesNotification](client, ChannelPattern("ticker.*"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+
+	err = receive(t, l.Err())
+	assert.True(t, errors.Is(err, ErrUnexpectedType), err)
+}
+
+func TestListen_Invalid(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	_, err := Listen[*models.TickerNotification](client, ChannelPattern("ticker.["))
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+
+	require.NoError(t, client.Close(context.Background()))
+	_, err = Listen[*models.TickerNotification](client, ChannelKindTicker)
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
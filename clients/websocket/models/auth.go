@@ -1,6 +1,13 @@
 package models
 
+import "time"
+
 type Authentication struct {
 	AccessToken  string
 	RefreshToken string
+	// ExpiresIn is the lifetime of AccessToken, which expires at ExpiresAt
+	ExpiresIn time.Duration
+	ExpiresAt time.Time
+	// Scope is the space separated scope granted to AccessToken
+	Scope string
 }
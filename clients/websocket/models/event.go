@@ -1,9 +1,42 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Event is wrapper of received event
 type Event struct {
 	Channel string          `json:"channel"`
 	Data    json.RawMessage `json:"data"`
 }
+
+// Heartbeat is the params of heartbeat messages sent by the server
+type Heartbeat struct {
+	Type string `json:"type"`
+}
+
+// ConnectionEvent describes a change in the connection lifecycle
+type ConnectionEvent struct {
+	// Attempt is the retry number for connecting and reconnecting events
+	Attempt int
+	// Delay is the wait before the next dial for reconnecting events
+	Delay time.Duration
+	// Err is the cause for disconnected, reconnecting and gave_up events
+	Err  error
+	Time time.Time
+}
+
+// OrderBookGap describes a book channel notification that did not follow the
+// last applied change, or left the book crossed
+type OrderBookGap struct {
+	Channel        string
+	InstrumentName string
+	// ChangeID is the last applied change
+	ChangeID int64
+	// PrevChangeID is the previous change of the delta, for a gap
+	PrevChangeID int64
+	Crossed      bool
+	Err          error
+	Time         time.Time
+}
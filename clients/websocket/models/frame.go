@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/BestNathan/deribit-api/pkg/frame"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The frame types are defined in pkg/frame, so that the shared configuration
+// does not depend on the websocket client
+type (
+	FrameDirection   = frame.Direction
+	Frame            = frame.Frame
+	FrameID          = frame.ID
+	FrameHandler     = frame.Handler
+	FrameInterceptor = frame.Interceptor
+)
+
+const (
+	FrameOutbound = frame.Outbound
+	FrameInbound  = frame.Inbound
+)
+
+// ChainFrameInterceptors returns handler wrapped by interceptors, the first
+// being the outermost
+func ChainFrameInterceptors(handler FrameHandler, interceptors ...FrameInterceptor) FrameHandler {
+	return frame.Chain(handler, interceptors...)
+}
+
+// parseFrame fills the ID and Method of f from its Data
+func parseFrame(f *Frame) {
+	var header struct {
+		ID     *jsonrpc2.ID `json:"id"`
+		Method string       `json:"method"`
+	}
+	if json.Unmarshal(f.Data, &header) != nil {
+		return
+	}
+
+	f.ID, f.Method = nil, header.Method
+	if id := header.ID; id != nil {
+		f.ID = &FrameID{Num: id.Num, Str: id.Str, IsString: id.IsString}
+	}
+}
@@ -2,8 +2,11 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/coder/websocket/wsjson"
@@ -12,23 +15,50 @@ import (
 // ObjectStream is a jsonrpc2.ObjectStream that uses a WebSocket to
 // send and receive JSON-RPC 2.0 objects.
 type ObjectStream struct {
-	conn *websocket.Conn
+	conn  *websocket.Conn
+	write FrameHandler
+	read  FrameHandler
 }
 
 // NewObjectStream creates a new jsonrpc2.ObjectStream for sending and
-// receiving JSON-RPC 2.0 objects over a WebSocket.
-func NewObjectStream(conn *websocket.Conn) ObjectStream {
-	return ObjectStream{conn: conn}
+// receiving JSON-RPC 2.0 objects over a WebSocket, passing every frame
+// through interceptors.
+func NewObjectStream(conn *websocket.Conn, interceptors ...FrameInterceptor) ObjectStream {
+	t := ObjectStream{conn: conn}
+	if len(interceptors) > 0 {
+		t.write = ChainFrameInterceptors(t.writeFrame, interceptors...)
+		t.read = ChainFrameInterceptors(t.readFrame, interceptors...)
+	}
+	return t
 }
 
 // WriteObject implements jsonrpc2.ObjectStream.
 func (t ObjectStream) WriteObject(obj interface{}) error {
-	return wsjson.Write(context.Background(), t.conn, obj)
+	if t.write == nil {
+		return wsjson.Write(context.Background(), t.conn, obj)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	frame := &Frame{Direction: FrameOutbound, Data: data, Time: time.Now()}
+	parseFrame(frame)
+	return t.write(frame)
 }
 
 // ReadObject implements jsonrpc2.ObjectStream.
 func (t ObjectStream) ReadObject(v interface{}) error {
-	err := wsjson.Read(context.Background(), t.conn, v)
+	var err error
+	if t.read == nil {
+		err = wsjson.Read(context.Background(), t.conn, v)
+	} else {
+		frame := &Frame{Direction: FrameInbound}
+		if err = t.read(frame); err == nil {
+			err = json.Unmarshal(frame.Data, v)
+		}
+	}
+
 	var e *websocket.CloseError
 	if errors.As(err, &e) {
 		if e.Code == websocket.StatusNormalClosure && e.Error() == io.ErrUnexpectedEOF.Error() {
@@ -43,3 +73,24 @@ func (t ObjectStream) ReadObject(v interface{}) error {
 func (t ObjectStream) Close() error {
 	return t.conn.Close(websocket.StatusNormalClosure, "")
 }
+
+// writeFrame is the innermost outbound FrameHandler
+func (t ObjectStream) writeFrame(frame *Frame) error {
+	return t.conn.Write(context.Background(), websocket.MessageText, frame.Data)
+}
+
+// readFrame is the innermost inbound FrameHandler
+func (t ObjectStream) readFrame(frame *Frame) error {
+	typ, data, err := t.conn.Read(context.Background())
+	if err != nil {
+		return err
+	}
+	if typ != websocket.MessageText {
+		_ = t.conn.Close(websocket.StatusUnsupportedData, "expected text message")
+		return fmt.Errorf("expected text message for JSON but got: %v", typ)
+	}
+
+	frame.Data, frame.Time = data, time.Now()
+	parseFrame(frame)
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+// SubscriptionStatus is the outcome of subscribing or unsubscribing a channel
+type SubscriptionStatus string
+
+const (
+	// SubscriptionConfirmed means the server included the channel in its response
+	SubscriptionConfirmed SubscriptionStatus = "confirmed"
+	// SubscriptionRejected means the server answered without the channel
+	SubscriptionRejected SubscriptionStatus = "rejected"
+	// SubscriptionPending means the channel is queued until the client is
+	// connected, or authenticated for private channels
+	SubscriptionPending SubscriptionStatus = "pending"
+	// SubscriptionFailed means the request failed, see Err
+	SubscriptionFailed SubscriptionStatus = "failed"
+)
+
+// SubscriptionResult reports the status of one channel of a subscribe or
+// unsubscribe request
+type SubscriptionResult struct {
+	Channel string
+	Status  SubscriptionStatus
+	Err     error
+}
+
+// SubscribeProgress reports one subscribe request of a larger subscription,
+// which is sent in chunks
+type SubscribeProgress struct {
+	// Results of the channels of the request
+	Results []SubscriptionResult
+	// Done counts the channels requested so far out of Total
+	Done  int
+	Total int
+}
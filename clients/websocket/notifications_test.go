@@ -0,0 +1,191 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notificationFixture reads a subscription message of testdata/notifications
+func notificationFixture(t *testing.T, name string) (channel string, data json.RawMessage) {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "notifications", name))
+	require.NoError(t, err)
+
+	var msg struct {
+		Params struct {
+			Channel string          `json:"channel"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(b, &msg))
+	return msg.Params.Channel, msg.Params.Data
+}
+
+func decodeFixture(t *testing.T, name string) interface{} {
+	t.Helper()
+
+	channel, data := notificationFixture(t, name)
+	parsed, decoder, ok := newDecoderRegistry().lookup(channel)
+	require.True(t, ok, "no decoder for %s", channel)
+
+	v, err := decoder(parsed, data)
+	require.NoError(t, err)
+	return v
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestNotifications_Fixtures(t *testing.T) {
+	open := "open"
+	allowed := false
+
+	tests := []struct {
+		fixture string
+		want    interface{}
+	}{
+		{
+			fixture: "deribit_volatility_index.json",
+			want: &models.DeribitVolatilityIndexNotification{
+				Timestamp:  1619777946007,
+				Volatility: 129.36,
+				IndexName:  "btc_usd",
+			},
+		},
+		{
+			fixture: "instrument_state.json",
+			want: &models.InstrumentStateNotification{
+				Timestamp:      1553080940000,
+				State:          models.InstrumentStateCreated,
+				InstrumentName: "BTC-22MAR19",
+			},
+		},
+		{
+			fixture: "platform_state.json",
+			want:    &models.PlatformStateNotification{PriceIndex: "sol_usdc", Locked: true},
+		},
+		{
+			fixture: "platform_state_public_methods_state.json",
+			want:    &models.PlatformStateNotification{AllowUnauthenticatedPublicRequests: &allowed},
+		},
+		{
+			fixture: "chart_trades.json",
+			want: &models.ChartTradesNotification{
+				Tick:   1573645080000,
+				Open:   8869.79,
+				High:   8870.31,
+				Low:    8788.25,
+				Close:  8791.25,
+				Volume: 0.05219351,
+				Cost:   460,
+			},
+		},
+		{
+			fixture: "incremental_ticker_change.json",
+			want: &models.IncrementalTickerNotification{
+				Type:                   "change",
+				Timestamp:              1623060194302,
+				InstrumentName:         "BTC-PERPETUAL",
+				MarkPrice:              float(36446.7),
+				EstimatedDeliveryPrice: float(36441.9),
+				BestBidAmount:          float(4500),
+			},
+		},
+		{
+			fixture: "user_mmp_trigger.json",
+			want: &models.UserMMPTriggerNotification{
+				IndexName:   "btc_usd",
+				FrozenUntil: 1744275841000,
+				MMPGroup:    "MassQuoteBot7",
+			},
+		},
+		{
+			fixture: "user_access_log.json",
+			want: &models.UserAccessLogNotification{
+				ID:        243,
+				Timestamp: 1575876682576,
+				Log:       "success",
+				IP:        "127.0.0.1",
+				Country:   "Local Country",
+				City:      "Local Town",
+			},
+		},
+		{
+			fixture: "block_trade_confirmations.json",
+			want: &models.BlockTradeConfirmationsNotification{
+				Nonce:     "bt-468nha",
+				Timestamp: 1711468813551,
+				UserID:    7,
+				Role:      "maker",
+				AppName:   "Example Application",
+				Trades: []models.BlockTradeConfirmationTrade{
+					{Price: 70246.8, InstrumentName: "BTC-PERPETUAL", Direction: "buy", Amount: 10},
+					{Price: 1.2, InstrumentName: "BTC-10APR24-70000-C", Direction: "sell", Amount: 1},
+				},
+				State:             models.BlockTradeConfirmationState{Value: "initial", Timestamp: 1711468813551},
+				CounterpartyState: &models.BlockTradeConfirmationState{Value: "accepted", Timestamp: 1711468813560},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeFixture(t, tt.fixture))
+		})
+	}
+
+	snapshot := decodeFixture(t, "incremental_ticker_snapshot.json").(*models.IncrementalTickerNotification)
+	assert.Equal(t, &open, snapshot.State)
+	assert.Equal(t, float(36446.51), snapshot.MarkPrice)
+}
+
+func TestNotifications_IncrementalTickerApply(t *testing.T) {
+	// every field of the snapshot
+	var want models.TickerNotification
+	want.Timestamp = 1623060194301
+	want.Stats.Volume, want.Stats.Low, want.Stats.High = 22206.54, 34765.5, 36856.5
+	want.State = "open"
+	want.SettlementPrice = 35932.18
+	want.OpenInterest = 502097590
+	want.MinPrice, want.MaxPrice = 35898.37, 36991.72
+	want.MarkPrice, want.LastPrice = 36446.51, 36457.5
+	want.InstrumentName = "BTC-PERPETUAL"
+	want.IndexPrice, want.EstimatedDeliveryPrice = 36441.64, 36441.64
+	want.Funding8H, want.CurrentFunding = 0.0000211, 0
+	want.BestBidPrice, want.BestBidAmount = 36442.5, 5000
+	want.BestAskPrice, want.BestAskAmount = 36443, 100
+
+	var ticker models.TickerNotification
+	decodeFixture(t, "incremental_ticker_snapshot.json").(*models.IncrementalTickerNotification).Apply(&ticker)
+	assert.Equal(t, want, ticker)
+
+	decodeFixture(t, "incremental_ticker_change.json").(*models.IncrementalTickerNotification).Apply(&ticker)
+	want.Timestamp = 1623060194302
+	want.MarkPrice, want.EstimatedDeliveryPrice, want.BestBidAmount = 36446.7, 36441.9, 4500
+	assert.Equal(t, want, ticker)
+}
+
+func TestNotifications_Subscribe(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	channel, data := notificationFixture(t, "deribit_volatility_index.json")
+	sub, err := Subscribe[*models.DeribitVolatilityIndexNotification](context.Background(), client, channel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(channel, data)
+
+	n := receive(t, sub.C())
+	assert.Equal(t, 129.36, n.Volatility)
+	assert.Equal(t, "btc_usd", n.IndexName)
+}
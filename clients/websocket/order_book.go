@@ -0,0 +1,408 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/BestNathan/deribit-api/pkg/orderbook"
+)
+
+// ErrResyncGivenUp is reported on the Err of an OrderBook once its
+// ResyncPolicy gave up, the book stays out of sync afterwards
+var ErrResyncGivenUp = errors.New("websocket order book resync given up")
+
+const (
+	defaultOrderBookDepth       = 1000
+	defaultOrderBookMaxBuffered = 10000
+)
+
+// OrderBookResync selects how an OrderBook is rebuilt after a gap
+type OrderBookResync int
+
+const (
+	// ResyncResubscribe resubscribes the channel, whose first notification
+	// is a snapshot. A channel shared with other Subscriptions, Listeners or
+	// the Subscribe method of the client is resynced as by ResyncSnapshot.
+	ResyncResubscribe OrderBookResync = iota
+	// ResyncSnapshot seeds the book from public/get_order_book and applies
+	// the updates buffered meanwhile, for raw and aggregated channels
+	ResyncSnapshot
+)
+
+// OrderBookOptions configure WatchOrderBook
+type OrderBookOptions struct {
+	Resync OrderBookResync
+	// Depth of the snapshot of ResyncSnapshot, 1000 by default
+	Depth int
+	// MaxBuffered limits the updates buffered during a resync, the oldest
+	// are dropped beyond it, 10000 by default
+	MaxBuffered int
+	// ResyncPolicy retries failed resyncs, see DefaultResyncPolicy
+	ResyncPolicy deribit.ReconnectPolicy
+}
+
+// DefaultResyncPolicy retries a failed resync ten times, backing off from
+// 100ms up to 10s
+func DefaultResyncPolicy() *deribit.ExponentialBackoff {
+	return &deribit.ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     10,
+	}
+}
+
+// OrderBook is an orderbook.Book kept up to date from a book channel,
+// returned by WatchOrderBook
+type OrderBook struct {
+	*orderbook.Book
+
+	client  Subscriber
+	sub     *Subscription[interface{}]
+	opts    OrderBookOptions
+	resyncs chan *resyncResult
+
+	// owned by run
+	resyncing bool
+	retry     int
+	buffered  []*orderbook.Update
+}
+
+// resyncResult is the snapshot of ResyncSnapshot, or the error of a resync
+type resyncResult struct {
+	snapshot *orderbook.Update
+	err      error
+}
+
+// WatchOrderBook subscribes a raw, aggregated or grouped book channel and
+// applies its notifications to a new orderbook.Book, e.g.
+//
+//	book, err := WatchOrderBook(ctx, client, "book.BTC-PERPETUAL.raw", OrderBookOptions{})
+//
+// On a gap in the change IDs or a crossed book, EventOrderBookGap is emitted
+// and the book is resynced as selected by opts.Resync, failed resyncs are
+// retried by opts.ResyncPolicy. Notifications that cannot be applied and
+// failed resyncs are reported on Err. c is a DeribitWSClient or a Pool.
+func WatchOrderBook(ctx context.Context, c Subscriber, channel string, opts OrderBookOptions) (*OrderBook, error) {
+	parsed, err := ParseChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Kind != ChannelKindBook {
+		return nil, fmt.Errorf("%w: %s is not a book channel", ErrInvalidChannel, channel)
+	}
+
+	o, err := newOrderBook(c, channel, parsed.InstrumentName, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.subscribeStream(ctx, []string{channel}); err != nil {
+		_ = o.CloseContext(ctx)
+		return nil, err
+	}
+	return o, nil
+}
+
+// newOrderBook starts an OrderBook calling onChange, if set, from its first
+// update, the caller subscribes the channel on the server
+func newOrderBook(c Subscriber, channel, instrument string, opts OrderBookOptions, onChange orderbook.ChangeFunc) (*OrderBook, error) {
+	if opts.Depth <= 0 {
+		opts.Depth = defaultOrderBookDepth
+	}
+	if opts.MaxBuffered <= 0 {
+		opts.MaxBuffered = defaultOrderBookMaxBuffered
+	}
+	if opts.ResyncPolicy == nil {
+		opts.ResyncPolicy = DefaultResyncPolicy()
+	}
+
+	sub, err := newDecodingSubscription(c, channel, decodeBookDecimal(channel))
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OrderBook{
+		Book:    orderbook.New(instrument),
+		client:  c,
+		sub:     sub,
+		opts:    opts,
+		resyncs: make(chan *resyncResult),
+	}
+	if onChange != nil {
+		o.OnChange(onChange)
+	}
+	if !c.spawn(o.run) {
+		o.sub.release()
+		return nil, ErrClientClosed
+	}
+	return o, nil
+}
+
+// Channel returns the subscribed book channel
+func (o *OrderBook) Channel() string {
+	return o.sub.Channel()
+}
+
+// Err delivers the notifications that could not be applied
+func (o *OrderBook) Err() <-chan error {
+	return o.sub.Err()
+}
+
+// Done is closed once the OrderBook is closed
+func (o *OrderBook) Done() <-chan struct{} {
+	return o.sub.Done()
+}
+
+// Close stops updating the book and unsubscribes its channel, the book keeps
+// its last state
+func (o *OrderBook) Close() error {
+	return o.sub.Close()
+}
+
+// CloseContext is Close with a context for the unsubscribe request
+func (o *OrderBook) CloseContext(ctx context.Context) error {
+	return o.sub.CloseContext(ctx)
+}
+
+func (o *OrderBook) run() {
+	for {
+		select {
+		case notification, ok := <-o.sub.C():
+			if !ok {
+				return
+			}
+			update, err := bookUpdate(notification)
+			if err != nil {
+				o.fail(err)
+				continue
+			}
+			o.handle(update)
+		case result := <-o.resyncs:
+			o.resynced(result)
+		}
+	}
+}
+
+// handle applies update, or buffers it during a resync
+func (o *OrderBook) handle(update *orderbook.Update) {
+	if !o.resyncing {
+		o.apply(update)
+		return
+	}
+
+	if o.opts.Resync == ResyncResubscribe && update.Snapshot {
+		// the resubscribed channel starts over, earlier updates are stale
+		o.resyncing, o.buffered = false, nil
+		o.apply(update)
+		return
+	}
+	if len(o.buffered) >= o.opts.MaxBuffered {
+		o.buffered[0] = nil
+		o.buffered = o.buffered[1:]
+	}
+	o.buffered = append(o.buffered, update)
+}
+
+// apply applies update, starting a resync on a gap or a crossed book
+func (o *OrderBook) apply(update *orderbook.Update) {
+	err := o.Apply(update)
+	if err == nil {
+		return
+	}
+
+	var gap *orderbook.GapError
+	switch {
+	case errors.As(err, &gap):
+		o.resync(update, &websocketmodels.OrderBookGap{ChangeID: gap.ChangeID, PrevChangeID: gap.PrevChangeID, Err: err})
+	case errors.Is(err, orderbook.ErrCrossed):
+		o.resync(nil, &websocketmodels.OrderBookGap{ChangeID: update.ChangeID, Crossed: true, Err: err})
+	default:
+		o.fail(err)
+	}
+}
+
+// resync emits the gap and rebuilds the book, pending is the rejected update
+func (o *OrderBook) resync(pending *orderbook.Update, gap *websocketmodels.OrderBookGap) {
+	gap.Channel, gap.InstrumentName, gap.Time = o.Channel(), o.Instrument(), time.Now()
+	o.client.Emit(EventOrderBookGap, gap)
+
+	o.resyncing, o.retry, o.buffered = true, 0, nil
+	if pending != nil {
+		o.buffered = append(o.buffered, pending)
+	}
+	o.startResync(0)
+}
+
+// startResync fetches the snapshot or resubscribes after delay
+func (o *OrderBook) startResync(delay time.Duration) {
+	ctx := o.client.defaultContext()
+	fetch := func() { o.fetchSnapshot(ctx) }
+	if o.opts.Resync == ResyncResubscribe {
+		fetch = func() { o.resubscribe(ctx) }
+	}
+
+	if !o.client.spawn(func() {
+		if o.wait(delay) {
+			fetch()
+		}
+	}) {
+		o.fail(fmt.Errorf("resync: %w", ErrClientClosed))
+	}
+}
+
+// resynced seeds the book with the fetched snapshot and applies the buffered
+// updates following it
+func (o *OrderBook) resynced(result *resyncResult) {
+	if !o.resyncing {
+		// already seeded by a snapshot notification
+		return
+	}
+	if result.err != nil {
+		// updates stay buffered until the retry, they would only detect the
+		// gap again
+		o.retry++
+		delay, ok := o.opts.ResyncPolicy.NextDelay(o.retry)
+		if !ok {
+			o.opts.ResyncPolicy.GiveUp(o.retry-1, result.err)
+			o.fail(fmt.Errorf("%w after %d retries: %w", ErrResyncGivenUp, o.retry-1, result.err))
+			return
+		}
+		o.fail(fmt.Errorf("resync: %w", result.err))
+		o.startResync(delay)
+		return
+	}
+	if result.snapshot == nil {
+		// resubscribed, the snapshot comes as a notification
+		return
+	}
+
+	buffered := o.buffered
+	o.resyncing, o.buffered = false, nil
+	o.apply(result.snapshot)
+	for _, update := range buffered {
+		o.handle(update)
+	}
+}
+
+func (o *OrderBook) fetchSnapshot(ctx context.Context) {
+	var response models.GetOrderBookResponse
+	err := o.client.CallContext(ctx, "public/get_order_book", &models.GetOrderBookParams{
+		InstrumentName: o.Instrument(),
+		Depth:          o.opts.Depth,
+	}, &response)
+
+	result := &resyncResult{err: err}
+	if err == nil {
+		result.snapshot = orderbook.FromOrderBook(&response)
+	}
+	o.report(result)
+}
+
+// resubscribe resubscribes the channel, unless other Subscriptions, Listeners
+// or the client use it as well, they would miss notifications meanwhile and
+// the book is seeded from a snapshot instead
+func (o *OrderBook) resubscribe(ctx context.Context) {
+	if !o.client.streamRegistry().exclusive(o.Channel(), o.sub.id) {
+		o.fetchSnapshot(ctx)
+		return
+	}
+
+	channels := []string{o.Channel()}
+
+	_, err := o.client.UnsubscribeContext(ctx, channels)
+	if err == nil && o.closed() {
+		return
+	}
+	if err == nil {
+		_, err = o.client.subscribeStream(ctx, channels)
+	}
+	if err == nil && o.closed() {
+		// closed while subscribing, the Subscription no longer unsubscribes
+		_, err = o.client.UnsubscribeContext(ctx, channels)
+	}
+	if err != nil {
+		o.report(&resyncResult{err: err})
+	}
+}
+
+// report passes the result of a resync to run
+func (o *OrderBook) report(result *resyncResult) {
+	select {
+	case o.resyncs <- result:
+	case <-o.Done():
+	}
+}
+
+// wait waits for delay, false when the OrderBook is closed meanwhile
+func (o *OrderBook) wait(delay time.Duration) bool {
+	if delay <= 0 {
+		return !o.closed()
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-o.Done():
+		return false
+	}
+}
+
+func (o *OrderBook) closed() bool {
+	select {
+	case <-o.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *OrderBook) fail(err error) {
+	o.sub.fail(fmt.Errorf("channel %s: %w", o.Channel(), err))
+}
+
+// bookUpdate converts the notifications of the book channels
+func bookUpdate(notification interface{}) (*orderbook.Update, error) {
+	switch n := notification.(type) {
+	case *models.OrderBookRawNotification:
+		return orderbook.FromRawNotification(n), nil
+	case *models.OrderBookNotification:
+		return orderbook.FromNotification(n), nil
+	case *models.OrderBookRawDecimalNotification:
+		return orderbook.FromRawDecimalNotification(n), nil
+	case *models.OrderBookDecimalNotification:
+		return orderbook.FromDecimalNotification(n), nil
+	case *models.OrderBookGroupNotification:
+		return orderbook.FromGroupNotification(n), nil
+	default:
+		return nil, fmt.Errorf("%w: %T is not a book notification", ErrUnexpectedType, notification)
+	}
+}
+
+// decodeBookDecimal returns the decoder of the notifications of an OrderBook,
+// which decodes raw and aggregated books with exact decimals instead of the
+// float64 of the notifications of the client. Grouped books are decimal
+// already.
+func decodeBookDecimal(name string) func(data json.RawMessage) (interface{}, error) {
+	channel, err := ParseChannel(name)
+	if err != nil || channel.Grouped() {
+		return nil
+	}
+
+	decode := decodeOrderBookDecimal
+	if channel.Interval == IntervalRaw {
+		decode = decodeOrderBookRawDecimal
+	}
+	return func(data json.RawMessage) (interface{}, error) {
+		return decode(channel, data)
+	}
+}
@@ -0,0 +1,388 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/BestNathan/deribit-api/pkg/orderbook"
+	"github.com/shopspring/decimal"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rawBookChannel = "book.BTC-PERPETUAL.raw"
+
+func TestOrderBook_Raw(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "BTC-PERPETUAL", book.Instrument())
+	assert.Equal(t, []string{rawBookChannel}, client.Subscriptions())
+
+	changes := make(chan int64, 2)
+	book.OnChange(func(_ *orderbook.Book, update *orderbook.Update) {
+		changes <- update.ChangeID
+	})
+
+	s.Publish(rawBookChannel, map[string]interface{}{
+		"instrument_name": "BTC-PERPETUAL",
+		"change_id":       1,
+		"bids":            []interface{}{[]interface{}{"new", 100.0, 10.0}},
+		"asks":            []interface{}{[]interface{}{"new", 101.0, 5.0}},
+	})
+	s.Publish(rawBookChannel, map[string]interface{}{
+		"instrument_name": "BTC-PERPETUAL",
+		"prev_change_id":  1,
+		"change_id":       2,
+		"bids":            []interface{}{[]interface{}{"change", 100.0, 7.0}},
+		"asks":            []interface{}{},
+	})
+	assert.Equal(t, int64(1), receive(t, changes))
+	assert.Equal(t, int64(2), receive(t, changes))
+
+	bid, ok := book.BestBid()
+	require.True(t, ok)
+	assert.True(t, decimal.NewFromInt(7).Equal(bid.Amount))
+	spread, ok := book.Spread()
+	require.True(t, ok)
+	assert.True(t, decimal.NewFromInt(1).Equal(spread))
+
+	require.NoError(t, book.Close())
+	assert.Empty(t, client.Subscriptions())
+	select {
+	case <-book.Done():
+	case <-time.After(time.Second):
+		t.Fatal("book not done")
+	}
+}
+
+func TestOrderBook_ExactValues(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	sub, err := Subscribe[*models.OrderBookRawNotification](context.Background(), client, rawBookChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	defer book.Close()
+
+	changes := make(chan int64, 1)
+	book.OnChange(func(_ *orderbook.Book, update *orderbook.Update) {
+		changes <- update.ChangeID
+	})
+
+	// neither value round-trips through a float64
+	s.Publish(rawBookChannel, json.RawMessage(`{
+		"instrument_name": "BTC-PERPETUAL",
+		"change_id": 1,
+		"bids": [["new",59786.123456789012345,12345678901234567890.5]],
+		"asks": []
+	}`))
+	assert.Equal(t, int64(1), receive(t, changes))
+
+	bid, ok := book.BestBid()
+	require.True(t, ok)
+	assert.Equal(t, "59786.123456789012345", bid.Price.String())
+	assert.Equal(t, "12345678901234567890.5", bid.Amount.String())
+
+	// other consumers of the channel still get the notification of the client
+	notification := receive(t, sub.C())
+	require.Len(t, notification.Bids, 1)
+	assert.Equal(t, 59786.123456789012345, notification.Bids[0].Price)
+}
+
+func TestOrderBook_UnknownAction(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, map[string]interface{}{
+		"change_id": 1,
+		"bids":      []interface{}{[]interface{}{"move", 100.0, 10.0}},
+		"asks":      []interface{}{},
+	})
+	assert.ErrorIs(t, receive(t, book.Err()), orderbook.ErrUnknownAction)
+}
+
+func TestOrderBook_InvalidChannel(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	_, err := WatchOrderBook(context.Background(), client, tickerChannel, OrderBookOptions{})
+	assert.ErrorIs(t, err, ErrInvalidChannel)
+}
+
+// rawBook returns the data of a raw book notification, a snapshot when prev
+// is 0
+func rawBook(prev, change int64, bids, asks []interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"instrument_name": "BTC-PERPETUAL",
+		"change_id":       change,
+		"bids":            append([]interface{}{}, bids...),
+		"asks":            append([]interface{}{}, asks...),
+	}
+	if prev != 0 {
+		data["prev_change_id"] = prev
+	}
+	return data
+}
+
+func bookItem(action string, price, amount float64) []interface{} {
+	return []interface{}{action, price, amount}
+}
+
+// watchGaps returns the gaps emitted by client
+func watchGaps(client *DeribitWSClient) <-chan *websocketmodels.OrderBookGap {
+	gaps := make(chan *websocketmodels.OrderBookGap, 4)
+	client.On(EventOrderBookGap, func(gap *websocketmodels.OrderBookGap) {
+		gaps <- gap
+	})
+	return gaps
+}
+
+func waitChangeID(t *testing.T, book *OrderBook, changeID int64) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return book.Synced() && book.ChangeID() == changeID
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestOrderBook_GapResubscribe(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	gaps := watchGaps(client)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, []interface{}{bookItem("new", 100, 10)}, []interface{}{bookItem("new", 101, 5)}))
+	waitChangeID(t, book, 1)
+
+	s.Publish(rawBookChannel, rawBook(5, 6, []interface{}{bookItem("change", 100, 1)}, nil))
+	gap := receive(t, gaps)
+	assert.Equal(t, rawBookChannel, gap.Channel)
+	assert.Equal(t, "BTC-PERPETUAL", gap.InstrumentName)
+	assert.Equal(t, int64(1), gap.ChangeID)
+	assert.Equal(t, int64(5), gap.PrevChangeID)
+	assert.False(t, gap.Crossed)
+	assert.ErrorIs(t, gap.Err, orderbook.ErrGap)
+
+	require.Eventually(t, func() bool {
+		return s.Calls("public/unsubscribe") == 1 && s.Calls("public/subscribe") == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{rawBookChannel}, client.Subscriptions())
+
+	// stale until the snapshot of the new subscription
+	s.Publish(rawBookChannel, rawBook(6, 7, []interface{}{bookItem("change", 100, 2)}, nil))
+	s.Publish(rawBookChannel, rawBook(0, 10, []interface{}{bookItem("new", 99, 1)}, []interface{}{bookItem("new", 100, 1)}))
+	s.Publish(rawBookChannel, rawBook(10, 11, []interface{}{bookItem("change", 99, 2)}, nil))
+	waitChangeID(t, book, 11)
+
+	bid, ok := book.BestBid()
+	require.True(t, ok)
+	assert.Equal(t, "99@2", bid.Price.String()+"@"+bid.Amount.String())
+	assert.Equal(t, 1, book.Len(orderbook.Bid))
+}
+
+func TestOrderBook_GapSnapshot(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"instrument_name": "BTC-PERPETUAL",
+			"change_id":       7,
+			"bids":            [][]float64{{100, 3}},
+			"asks":            [][]float64{{101, 4}},
+		}, nil
+	})
+	client := newMockClient(t, s)
+	gaps := watchGaps(client)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{Resync: ResyncSnapshot, Depth: 50})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, []interface{}{bookItem("new", 100, 10)}, []interface{}{bookItem("new", 101, 5)}))
+	waitChangeID(t, book, 1)
+
+	// 6 is contained in the snapshot, 8 overlaps it
+	s.Publish(rawBookChannel, rawBook(5, 6, []interface{}{bookItem("change", 100, 1)}, nil))
+	s.Publish(rawBookChannel, rawBook(6, 8, []interface{}{bookItem("change", 100, 9)}, nil))
+	s.Publish(rawBookChannel, rawBook(8, 9, nil, []interface{}{bookItem("change", 101, 1)}))
+
+	gap := receive(t, gaps)
+	assert.Equal(t, int64(5), gap.PrevChangeID)
+	waitChangeID(t, book, 9)
+
+	assert.JSONEq(t, `{"instrument_name":"BTC-PERPETUAL","depth":50}`, string(s.LastParams("public/get_order_book")))
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+
+	bid, _ := book.BestBid()
+	ask, _ := book.BestAsk()
+	assert.Equal(t, "100@9", bid.Price.String()+"@"+bid.Amount.String())
+	assert.Equal(t, "101@1", ask.Price.String()+"@"+ask.Amount.String())
+}
+
+func TestOrderBook_Crossed(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	gaps := watchGaps(client)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, []interface{}{bookItem("new", 101, 1)}, []interface{}{bookItem("new", 100, 1)}))
+
+	gap := receive(t, gaps)
+	assert.True(t, gap.Crossed)
+	assert.Equal(t, int64(1), gap.ChangeID)
+	assert.ErrorIs(t, gap.Err, orderbook.ErrCrossed)
+	assert.False(t, book.Synced())
+	require.Eventually(t, func() bool {
+		return s.Calls("public/unsubscribe") == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// spawnCounter counts the goroutines started through the client
+type spawnCounter struct {
+	*DeribitWSClient
+	spawned atomic.Int32
+}
+
+func (c *spawnCounter) spawn(f func()) bool {
+	c.spawned.Add(1)
+	return c.DeribitWSClient.spawn(f)
+}
+
+func TestOrderBook_SpawnsThroughClient(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"instrument_name": "BTC-PERPETUAL", "change_id": 7}, nil
+	})
+	client := &spawnCounter{DeribitWSClient: newMockClient(t, s)}
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{Resync: ResyncSnapshot})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), client.spawned.Load())
+
+	s.Publish(rawBookChannel, rawBook(0, 1, nil, nil))
+	s.Publish(rawBookChannel, rawBook(5, 6, nil, nil))
+	waitChangeID(t, book, 7)
+	assert.Equal(t, int32(2), client.spawned.Load())
+
+	require.NoError(t, client.Close(context.Background()))
+	assert.True(t, book.closed())
+
+	_, err = WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestOrderBook_ResyncBackoff(t *testing.T) {
+	s := newMockServer(t)
+	var fetches atomic.Int32
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		if fetches.Add(1) < 3 {
+			return nil, &jsonrpc2.Error{Code: 10028, Message: "too_many_requests"}
+		}
+		return map[string]interface{}{"instrument_name": "BTC-PERPETUAL", "change_id": 7}, nil
+	})
+	client := newMockClient(t, s)
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{
+		Resync:       ResyncSnapshot,
+		ResyncPolicy: &deribit.ExponentialBackoff{InitialInterval: 50 * time.Millisecond, MaxAttempts: 5},
+	})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, nil, nil))
+	s.Publish(rawBookChannel, rawBook(5, 6, nil, nil))
+	assert.ErrorContains(t, receive(t, book.Err()), "too_many_requests")
+
+	// buffered during the backoff instead of starting a new resync
+	s.Publish(rawBookChannel, rawBook(6, 8, nil, nil))
+	s.Publish(rawBookChannel, rawBook(8, 9, nil, nil))
+	assert.ErrorContains(t, receive(t, book.Err()), "too_many_requests")
+	waitChangeID(t, book, 9)
+	assert.Equal(t, int32(3), fetches.Load())
+}
+
+func TestOrderBook_ResyncGivesUp(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: 10028, Message: "too_many_requests"}
+	})
+	client := newMockClient(t, s)
+
+	gaveUp := make(chan int, 1)
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{
+		Resync: ResyncSnapshot,
+		ResyncPolicy: &deribit.ExponentialBackoff{
+			InitialInterval: time.Millisecond,
+			MaxAttempts:     2,
+			OnGiveUp:        func(retries int, _ error) { gaveUp <- retries },
+		},
+	})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, nil, nil))
+	s.Publish(rawBookChannel, rawBook(5, 6, nil, nil))
+	assert.Equal(t, 2, receive(t, gaveUp))
+
+	var last error
+	for err := range book.Err() {
+		if last = err; errors.Is(err, ErrResyncGivenUp) {
+			break
+		}
+	}
+	assert.ErrorIs(t, last, ErrResyncGivenUp)
+
+	s.Publish(rawBookChannel, rawBook(6, 7, nil, nil))
+	s.Publish(rawBookChannel, rawBook(9, 10, nil, nil))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 3, s.Calls("public/get_order_book"))
+	assert.False(t, book.Synced())
+}
+
+func TestOrderBook_GapResubscribeShared(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/get_order_book", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"instrument_name": "BTC-PERPETUAL", "change_id": 7}, nil
+	})
+	client := newMockClient(t, s)
+
+	other, err := Subscribe[interface{}](context.Background(), client, rawBookChannel)
+	require.NoError(t, err)
+	defer other.Close()
+
+	book, err := WatchOrderBook(context.Background(), client, rawBookChannel, OrderBookOptions{})
+	require.NoError(t, err)
+	defer book.Close()
+
+	s.Publish(rawBookChannel, rawBook(0, 1, nil, nil))
+	s.Publish(rawBookChannel, rawBook(5, 6, nil, nil))
+	s.Publish(rawBookChannel, rawBook(7, 8, nil, nil))
+	waitChangeID(t, book, 8)
+
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+	assert.Equal(t, 1, s.Calls("public/get_order_book"))
+	for range 3 {
+		receive(t, other.C())
+	}
+}
@@ -0,0 +1,622 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/chuckpreslar/emission"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoConnection is returned by a Pool without a connection for a call
+var ErrNoConnection = errors.New("websocket pool has no connection")
+
+const defaultPoolSize = 2
+
+// PoolOptions configure a Pool
+type PoolOptions struct {
+	// Size is the number of connections, 2 by default
+	Size int
+	// Authenticated is the number of connections authenticated with the
+	// credential of the configuration, 1 by default when a credential is set.
+	// Private channels and private methods use these connections only.
+	Authenticated int
+	// Strategy picks the connection of a channel, LeastChannels by default
+	Strategy PoolStrategy
+}
+
+// PoolConn describes a connection of a Pool to a PoolStrategy
+type PoolConn struct {
+	Index         int
+	Channels      int
+	Authenticated bool
+}
+
+// PoolStrategy picks the connection of channel and returns its index in
+// candidates. Candidates are the connections allowed for the channel, the
+// connected ones if any, and never empty.
+type PoolStrategy func(channel string, candidates []PoolConn) int
+
+// LeastChannels picks the connection with the fewest channels
+func LeastChannels(_ string, candidates []PoolConn) int {
+	best := 0
+	for i, conn := range candidates {
+		if conn.Channels < candidates[best].Channels {
+			best = i
+		}
+	}
+	return best
+}
+
+// HashInstrument picks the connection by a hash of the instrument, index or
+// currency of the channel, so every channel of an instrument shares a
+// connection
+func HashInstrument(channel string, candidates []PoolConn) int {
+	key := channel
+	if parsed, ok := parseChannel(channel); ok {
+		switch {
+		case parsed.InstrumentName != "":
+			key = parsed.InstrumentName
+		case parsed.IndexName != "":
+			key = parsed.IndexName
+		case parsed.Currency != "":
+			key = parsed.Currency
+		}
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(candidates)))
+}
+
+// Pool shards the channels of one account across several connections. It
+// has the listener API of a DeribitWSClient, notifications of every
+// connection are delivered to the listeners and typed streams of the Pool.
+// The channels of a connection that drops are moved to the connected ones.
+type Pool struct {
+	ctx      context.Context
+	members  []*poolMember
+	strategy PoolStrategy
+	logger   *logrus.Logger
+
+	// mu guards channels and closed, channels maps the channels requested
+	// with Subscribe to their connection
+	mu       sync.RWMutex
+	channels map[string]*poolMember
+	closed   bool
+	wg       sync.WaitGroup
+
+	emitter  *emission.Emitter
+	matchers *matcherSet
+	streams  *streamRegistry
+	cache    *notificationCache
+}
+
+type poolMember struct {
+	index         int
+	client        *DeribitWSClient
+	authenticated bool
+	// channels counts the channels assigned to the member, guarded by Pool.mu
+	channels int
+}
+
+// NewPool returns a Pool of opts.Size clients of cfg, started unless
+// cfg.AutoStart is false
+func NewPool(cfg *deribit.Configuration, opts PoolOptions) *Pool {
+	ctx := cfg.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	authenticated := opts.Authenticated
+	if authenticated <= 0 && cfg.Credential.ApiKey != "" {
+		authenticated = 1
+	}
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = LeastChannels
+	}
+
+	p := &Pool{
+		ctx:      ctx,
+		strategy: strategy,
+		logger:   cfg.Logger,
+		channels: make(map[string]*poolMember),
+		emitter:  emission.NewEmitter(),
+		matchers: newMatcherSet(),
+		streams:  newStreamRegistry(),
+		cache:    newNotificationCache(),
+	}
+
+	// Deribit limits the requests of an account, not of a connection
+	limiter := newSubscribeLimiter(cfg.WebsocketConfiguration)
+
+	for i := 0; i < size; i++ {
+		wsCfg := *cfg.WebsocketConfiguration
+		wsCfg.AutoStart = false
+
+		memberCfg := *cfg
+		memberCfg.WebsocketConfiguration = &wsCfg
+		if i >= authenticated {
+			memberCfg.Credential = deribit.Credential{}
+		}
+
+		m := &poolMember{index: i, client: NewDeribitWsClient(&memberCfg), authenticated: i < authenticated}
+		m.client.subscribeLimiter = limiter
+		m.client.forward = func(channel string, notification interface{}, data json.RawMessage, received time.Time) {
+			p.forward(m, channel, notification, data, received)
+		}
+		m.client.On(EventDisconnected, func(*websocketmodels.ConnectionEvent) {
+			p.spawn(func() { p.rebalance(m) })
+		})
+		p.members = append(p.members, m)
+	}
+
+	if cfg.AutoStart {
+		if err := p.Start(ctx); err != nil {
+			p.logger.WithContext(ctx).Warnln("websocket pool start fail", err)
+			panic(err)
+		}
+	}
+
+	return p
+}
+
+// Start connects every client of the Pool, failures are returned joined
+func (p *Pool) Start(ctx context.Context) error {
+	var errs []error
+	for _, m := range p.members {
+		if err := m.client.Start(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("connection %d: %w", m.index, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close shuts down every client of the Pool, see Shutdown
+func (p *Pool) Close(ctx context.Context) error {
+	return p.Shutdown(ctx, DefaultShutdownOptions())
+}
+
+// Shutdown shuts down every client of the Pool with opts, logout and order
+// cancellation run on the authenticated connections only
+func (p *Pool) Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClientClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	p.streams.closeAll()
+
+	var errs []error
+	for _, m := range p.members {
+		if err := m.client.Shutdown(ctx, opts); err != nil {
+			errs = append(errs, fmt.Errorf("connection %d: %w", m.index, err))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// Clients returns the clients of the Pool
+func (p *Pool) Clients() []*DeribitWSClient {
+	clients := make([]*DeribitWSClient, len(p.members))
+	for i, m := range p.members {
+		clients[i] = m.client
+	}
+	return clients
+}
+
+// Public returns the connected client with the fewest channels
+func (p *Pool) Public() (*DeribitWSClient, error) {
+	return p.pickClient(false)
+}
+
+// Private returns a connected authenticated client, for trading and the
+// other private methods
+func (p *Pool) Private() (*DeribitWSClient, error) {
+	return p.pickClient(true)
+}
+
+func (p *Pool) pickClient(private bool) (*DeribitWSClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	candidates := p.candidatesLocked(private, nil, true)
+	if len(candidates) == 0 {
+		return nil, ErrNoConnection
+	}
+	return p.members[candidates[LeastChannels("", candidates)].Index].client, nil
+}
+
+// Call calls method on a connection of the Pool, see CallContext
+func (p *Pool) Call(method string, params interface{}, result interface{}) error {
+	return p.CallContext(p.defaultContext(), method, params, result)
+}
+
+// CallContext calls method on an authenticated connection if it is private
+// and on any connection otherwise
+func (p *Pool) CallContext(ctx context.Context, method string, params interface{}, result interface{}) error {
+	client, err := p.pickClient(isPrivateMethod(method))
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, method, params, result)
+}
+
+// Subscribe subscribes channels on the connections picked by the strategy,
+// see SubscribeContext
+func (p *Pool) Subscribe(channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return p.SubscribeContext(p.defaultContext(), channels)
+}
+
+// SubscribeContext assigns every new channel to a connection picked by the
+// strategy, private channels to authenticated connections only, and
+// subscribes them. A result is returned per channel, in order, rejected
+// channels are unassigned.
+func (p *Pool) SubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return p.subscribeAs(ctx, channels, true)
+}
+
+// subscribeStream is SubscribeContext for the typed streams, which keep
+// owning the channels they subscribed first
+func (p *Pool) subscribeStream(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return p.subscribeAs(ctx, channels, false)
+}
+
+func (p *Pool) subscribeAs(ctx context.Context, channels []string, explicit bool) ([]websocketmodels.SubscriptionResult, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+
+	groups := make(map[*poolMember][]string)
+	for _, channel := range channels {
+		m, ok := p.channels[channel]
+		if !ok {
+			if m = p.pickLocked(channel, nil); m == nil {
+				p.mu.Unlock()
+				return nil, fmt.Errorf("%w for channel %s", ErrNoConnection, channel)
+			}
+			p.assignLocked(channel, m)
+		}
+		groups[m] = append(groups[m], channel)
+	}
+	p.mu.Unlock()
+
+	if explicit {
+		p.streams.disown(channels)
+	}
+
+	results := p.perMember(groups, channels, func(m *poolMember, channels []string) []websocketmodels.SubscriptionResult {
+		results, _ := m.client.SubscribeContext(ctx, channels)
+		return results
+	})
+
+	p.mu.Lock()
+	for _, result := range results {
+		if result.Status == websocketmodels.SubscriptionRejected {
+			p.unassignLocked(result.Channel)
+		}
+	}
+	p.mu.Unlock()
+
+	return results, subscriptionError(results)
+}
+
+// Unsubscribe unsubscribes channels from their connections, see
+// UnsubscribeContext
+func (p *Pool) Unsubscribe(channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return p.UnsubscribeContext(p.defaultContext(), channels)
+}
+
+// UnsubscribeContext unassigns channels and unsubscribes them from their
+// connections. A result is returned per channel, in order.
+func (p *Pool) UnsubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	p.mu.Lock()
+	groups := make(map[*poolMember][]string)
+	for _, channel := range channels {
+		if m, ok := p.channels[channel]; ok {
+			groups[m] = append(groups[m], channel)
+			p.unassignLocked(channel)
+		}
+	}
+	p.mu.Unlock()
+
+	results := p.perMember(groups, channels, func(m *poolMember, channels []string) []websocketmodels.SubscriptionResult {
+		results, _ := m.client.UnsubscribeContext(ctx, channels)
+		return results
+	})
+	p.cache.forget(channels)
+	return results, subscriptionError(results)
+}
+
+// perMember runs call for the channels of every connection and returns the
+// results in the order of channels, channels of no connection are confirmed
+func (p *Pool) perMember(
+	groups map[*poolMember][]string,
+	channels []string,
+	call func(m *poolMember, channels []string) []websocketmodels.SubscriptionResult,
+) []websocketmodels.SubscriptionResult {
+	byChannel := make(map[string]websocketmodels.SubscriptionResult, len(channels))
+	for m, group := range groups {
+		for _, result := range call(m, group) {
+			byChannel[result.Channel] = result
+		}
+	}
+
+	results := make([]websocketmodels.SubscriptionResult, len(channels))
+	for i, channel := range channels {
+		result, ok := byChannel[channel]
+		if !ok {
+			result = websocketmodels.SubscriptionResult{Channel: channel, Status: websocketmodels.SubscriptionConfirmed}
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// Subscriptions returns the channels requested with Subscribe
+func (p *Pool) Subscriptions() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	channels := make([]string, 0, len(p.channels))
+	for channel := range p.channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Assignments returns the channels of every connection by index
+func (p *Pool) Assignments() map[int][]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	assignments := make(map[int][]string)
+	for channel, m := range p.channels {
+		assignments[m.index] = append(assignments[m.index], channel)
+	}
+	for _, channels := range assignments {
+		sort.Strings(channels)
+	}
+	return assignments
+}
+
+// On adds a listener to the notifications of every connection, see
+// DeribitWSClient.On. Connection events are emitted by the clients, see
+// Clients.
+func (p *Pool) On(event interface{}, listener interface{}) (emitter *emission.Emitter) {
+	p.cache.withReplay(event, func() {
+		emitter = p.matchers.on(p.emitter, event, listener)
+	}, replayListener(event, listener))
+	return
+}
+
+// Off removes a listener for an event
+func (p *Pool) Off(event interface{}, listener interface{}) *emission.Emitter {
+	return p.matchers.off(p.emitter, event, listener)
+}
+
+// Emit emits an event
+func (p *Pool) Emit(event interface{}, arguments ...interface{}) *emission.Emitter {
+	return p.emitter.Emit(event, arguments...)
+}
+
+// EnableCache keeps the latest notification of the channels matched by
+// matchers, see DeribitWSClient.EnableCache
+func (p *Pool) EnableCache(matchers ...ChannelMatcher) {
+	p.cache.enable(matchers)
+}
+
+// Latest returns the latest notification of channel if it is cached
+func (p *Pool) Latest(channel string) (CachedNotification, bool) {
+	return p.cache.latest(channel)
+}
+
+// SetDelivery sets the delivery options of channel on every connection, so
+// they still apply once the channel moved, see DeribitWSClient.SetDelivery
+func (p *Pool) SetDelivery(channel string, opts DeliveryOptions) {
+	for _, m := range p.members {
+		m.client.SetDelivery(channel, opts)
+	}
+}
+
+// SetDeliveryFor sets the delivery options of the channels matched by
+// matcher on every connection, see DeribitWSClient.SetDeliveryFor
+func (p *Pool) SetDeliveryFor(matcher ChannelMatcher, opts DeliveryOptions) {
+	for _, m := range p.members {
+		m.client.SetDeliveryFor(matcher, opts)
+	}
+}
+
+// DeliveryStats returns the counters of the delivery queue of channel on the
+// connection it is assigned to, they start over when the channel moves
+func (p *Pool) DeliveryStats(channel string) DeliveryStats {
+	p.mu.RLock()
+	m, ok := p.channels[channel]
+	p.mu.RUnlock()
+
+	if !ok {
+		return DeliveryStats{}
+	}
+	return m.client.DeliveryStats(channel)
+}
+
+// forward dispatches a notification of m, unless its channel is assigned to
+// another connection, e.g. while it is moved
+func (p *Pool) forward(m *poolMember, channel string, notification interface{}, data json.RawMessage, received time.Time) {
+	p.mu.RLock()
+	owner := p.channels[channel]
+	p.mu.RUnlock()
+
+	if owner != m {
+		return
+	}
+
+	var sinks []streamSink
+	p.cache.store(channel, notification, received, func() {
+		sinks = p.streams.sinks(channel)
+	})
+
+	p.matchers.emit(p.emitter, channel, notification)
+	deliverSinks(sinks, channel, notification, data)
+}
+
+// rebalance moves the channels of the disconnected m to connected members
+func (p *Pool) rebalance(m *poolMember) {
+	ctx := p.defaultContext()
+
+	p.mu.Lock()
+	moved := make(map[*poolMember][]string)
+	var released []string
+	for channel, owner := range p.channels {
+		if owner != m {
+			continue
+		}
+		target := p.pickLocked(channel, m)
+		if target == nil || !target.client.IsConnected() {
+			// nowhere to go, m renews it after reconnecting
+			continue
+		}
+		p.assignLocked(channel, target)
+		moved[target] = append(moved[target], channel)
+		released = append(released, channel)
+	}
+	p.mu.Unlock()
+
+	if len(released) == 0 {
+		return
+	}
+
+	// m is disconnected, so this only drops the channels from its renewals
+	if _, err := m.client.UnsubscribeContext(ctx, released); err != nil {
+		p.logger.WithContext(ctx).Debugln("websocket pool release channels", err)
+	}
+	for target, channels := range moved {
+		sort.Strings(channels)
+		if _, err := target.client.SubscribeContext(ctx, channels); err != nil {
+			p.logger.WithContext(ctx).Warnf("websocket pool move channels to connection %d: %v\n", target.index, err)
+		}
+	}
+	p.logger.WithContext(ctx).Debugf("websocket pool moved %d channels of connection %d\n", len(released), m.index)
+}
+
+// pickLocked picks the member of channel with the strategy, never exclude.
+// The caller must hold mu.
+func (p *Pool) pickLocked(channel string, exclude *poolMember) *poolMember {
+	candidates := p.candidatesLocked(isPrivateChannel(channel), exclude, true)
+	if len(candidates) == 0 {
+		candidates = p.candidatesLocked(isPrivateChannel(channel), exclude, false)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	i := p.strategy(channel, candidates)
+	if i < 0 || i >= len(candidates) {
+		i = 0
+	}
+	return p.members[candidates[i].Index]
+}
+
+// candidatesLocked returns the members allowed for private or public use,
+// connected ones only if connected is set. The caller must hold mu.
+func (p *Pool) candidatesLocked(private bool, exclude *poolMember, connected bool) []PoolConn {
+	var candidates []PoolConn
+	for _, m := range p.members {
+		if m == exclude || (private && !m.authenticated) {
+			continue
+		}
+		if connected && !m.client.IsConnected() {
+			continue
+		}
+		candidates = append(candidates, PoolConn{Index: m.index, Channels: m.channels, Authenticated: m.authenticated})
+	}
+	return candidates
+}
+
+// assignLocked assigns channel to m. The caller must hold mu.
+func (p *Pool) assignLocked(channel string, m *poolMember) {
+	p.unassignLocked(channel)
+	p.channels[channel] = m
+	m.channels++
+}
+
+// unassignLocked drops the assignment of channel. The caller must hold mu.
+func (p *Pool) unassignLocked(channel string) {
+	if m, ok := p.channels[channel]; ok {
+		m.channels--
+		delete(p.channels, channel)
+	}
+}
+
+// spawn runs f tracked by wg, it returns false without running f once the
+// Pool is closed
+func (p *Pool) spawn(f func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		f()
+	}()
+	return true
+}
+
+func (p *Pool) defaultContext() context.Context {
+	return context.WithoutCancel(p.ctx)
+}
+
+func (p *Pool) streamRegistry() *streamRegistry {
+	return p.streams
+}
+
+func (p *Pool) notificationCache() *notificationCache {
+	return p.cache
+}
+
+func (p *Pool) isClosed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.closed
+}
+
+func (p *Pool) isSubscribed(channel string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.channels[channel]
+	return ok
+}
@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockPool returns a started pool connected to s
+func newMockPool(t *testing.T, s *mockServer, opts PoolOptions, modify ...func(*deribit.Configuration)) *Pool {
+	t.Helper()
+
+	pool := NewPool(mockConfig(s, modify...), opts)
+	require.NoError(t, pool.Start(context.Background()))
+	t.Cleanup(func() {
+		_ = pool.Close(context.Background())
+	})
+
+	return pool
+}
+
+var poolChannels = []string{
+	"ticker.BTC-PERPETUAL.100ms",
+	"ticker.ETH-PERPETUAL.100ms",
+	"book.BTC-PERPETUAL.raw",
+	"book.ETH-PERPETUAL.raw",
+}
+
+func TestPool_Shards(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+
+	results, err := pool.Subscribe(poolChannels)
+	require.NoError(t, err)
+	require.Len(t, results, len(poolChannels))
+	for i, result := range results {
+		assert.Equal(t, poolChannels[i], result.Channel)
+	}
+
+	assignments := pool.Assignments()
+	assert.Len(t, assignments[0], 2)
+	assert.Len(t, assignments[1], 2)
+	for i, client := range pool.Clients() {
+		assert.Equal(t, assignments[i], client.ActiveSubscriptions())
+	}
+
+	var mu sync.Mutex
+	var received []string
+	done := make(chan struct{}, len(poolChannels))
+	pool.On(ChannelKindTicker, func(channel string, n *models.TickerNotification) {
+		mu.Lock()
+		received = append(received, channel)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	// the mock server publishes on every connection, the pool delivers the
+	// notification of the owning connection only
+	s.Publish("ticker.BTC-PERPETUAL.100ms", map[string]interface{}{"timestamp": 1})
+	s.Publish("ticker.ETH-PERPETUAL.100ms", map[string]interface{}{"timestamp": 2})
+	receive(t, done)
+	receive(t, done)
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	sort.Strings(received)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms", "ticker.ETH-PERPETUAL.100ms"}, received)
+	mu.Unlock()
+
+	_, err = pool.Unsubscribe(poolChannels[:2])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"book.BTC-PERPETUAL.raw", "book.ETH-PERPETUAL.raw"}, pool.Subscriptions())
+}
+
+func TestPool_PrivatePinned(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("private/get_positions", func(json.RawMessage) (interface{}, error) {
+		return []interface{}{}, nil
+	})
+	pool := newMockPool(t, s, PoolOptions{Size: 3}, withCredential)
+
+	clients := pool.Clients()
+	assert.True(t, clients[0].isAuthenticated())
+	assert.False(t, clients[1].isAuthenticated())
+	assert.False(t, clients[2].isAuthenticated())
+
+	_, err := pool.Subscribe([]string{"user.orders.any.any.raw", "user.trades.any.any.raw", tickerChannel})
+	require.NoError(t, err)
+	assignments := pool.Assignments()
+	assert.Equal(t, []string{"user.orders.any.any.raw", "user.trades.any.any.raw"}, assignments[0])
+
+	private, err := pool.Private()
+	require.NoError(t, err)
+	assert.Same(t, clients[0], private)
+
+	var positions []models.Position
+	require.NoError(t, pool.CallContext(context.Background(), "private/get_positions", &models.GetPositionsParams{Currency: "BTC"}, &positions))
+	assert.Equal(t, 1, s.Calls("private/get_positions"))
+}
+
+func TestPool_NoAuthenticatedConnection(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+
+	_, err := pool.Subscribe([]string{"user.orders.any.any.raw"})
+	assert.ErrorIs(t, err, ErrNoConnection)
+	_, err = pool.Private()
+	assert.ErrorIs(t, err, ErrNoConnection)
+}
+
+func TestPool_Rebalance(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2}, fastReconnect)
+
+	_, err := pool.Subscribe(poolChannels)
+	require.NoError(t, err)
+	moved := pool.Assignments()[0]
+	require.Len(t, moved, 2)
+
+	s.DropConnection(0)
+
+	require.Eventually(t, func() bool {
+		assignments := pool.Assignments()
+		return len(assignments[1]) == len(poolChannels) && len(pool.Clients()[1].ActiveSubscriptions()) == len(poolChannels)
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// the reconnected connection does not renew the moved channels
+	require.Eventually(t, pool.Clients()[0].IsConnected, 5*time.Second, 20*time.Millisecond)
+	assert.Empty(t, pool.Clients()[0].Subscriptions())
+}
+
+func TestPool_Subscription(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), pool, tickerChannel)
+	require.NoError(t, err)
+	assert.Equal(t, []string{tickerChannel}, pool.Subscriptions())
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 7})
+	assert.Equal(t, int64(7), receive(t, sub.C()).Timestamp)
+
+	require.NoError(t, sub.Close())
+	assert.Empty(t, pool.Subscriptions())
+
+	require.NoError(t, pool.Close(context.Background()))
+	_, err = Subscribe[*models.TickerNotification](context.Background(), pool, tickerChannel)
+	assert.ErrorIs(t, err, ErrClientClosed)
+	assert.ErrorIs(t, pool.Close(context.Background()), ErrClientClosed)
+}
+
+func TestPool_SubscriptionKeepsLaterPoolSubscription(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), pool, tickerChannel)
+	require.NoError(t, err)
+	_, err = pool.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+	assert.Equal(t, []string{tickerChannel}, pool.Subscriptions())
+}
+
+func TestPool_Delivery(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+	pool.SetDeliveryFor(ChannelKindTicker, DeliveryOptions{Policy: DeliveryCoalesce})
+	pool.SetDelivery(tickerChannel, DeliveryOptions{Policy: DeliveryDropOldest, Size: 4})
+
+	for _, client := range pool.Clients() {
+		client.deliveries.mu.Lock()
+		assert.Equal(t, DeliveryOptions{Policy: DeliveryDropOldest, Size: 4}, client.deliveries.optionsLocked(tickerChannel))
+		assert.Equal(t, DeliveryOptions{Policy: DeliveryCoalesce}, client.deliveries.optionsLocked("ticker.ETH-PERPETUAL.100ms"))
+		client.deliveries.mu.Unlock()
+	}
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), pool, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 7})
+	assert.Equal(t, int64(7), receive(t, sub.C()).Timestamp)
+	require.Eventually(t, func() bool {
+		return pool.DeliveryStats(tickerChannel).Delivered == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, DeliveryStats{}, pool.DeliveryStats("ticker.ETH-PERPETUAL.100ms"))
+}
+
+func TestPool_Cache(t *testing.T) {
+	s := newMockServer(t)
+	pool := newMockPool(t, s, PoolOptions{Size: 2})
+	pool.EnableCache()
+
+	_, err := pool.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 3})
+	require.Eventually(t, func() bool {
+		_, ok := pool.Latest(tickerChannel)
+		return ok
+	}, 2*time.Second, 10*time.Millisecond)
+
+	var replayed int64
+	pool.On(tickerChannel, func(n *models.TickerNotification) {
+		replayed = n.Timestamp
+	})
+	assert.Equal(t, int64(3), replayed)
+
+	_, err = pool.Unsubscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	_, ok := pool.Latest(tickerChannel)
+	assert.False(t, ok)
+}
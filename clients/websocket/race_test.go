@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file are meant to be run with -race, they hammer one
+// client from many goroutines while the server drops the connection.
+
+func TestRace_SubscribeCallReconnect(t *testing.T) {
+	s := newMockServer(t)
+	// unpaced, the test sends hundreds of subscribe requests
+	client := newMockClient(t, s, fastReconnect, withSubscribeLimits(0, deribit.RateLimit{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	const workers = 16
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				channel := fmt.Sprintf("ticker.INSTR-%d.100ms", (i+j)%8)
+				_, _ = client.SubscribeContext(ctx, []string{channel})
+				if j%3 == 0 {
+					_, _ = client.UnsubscribeContext(ctx, []string{channel})
+				}
+				_ = client.Subscriptions()
+				_ = client.ActiveSubscriptions()
+			}
+		}(i)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				// calls fail while the connection is down, they only need
+				// to be safe
+				_, _ = client.GetTimeContext(ctx)
+				_ = client.IsConnected()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 5; j++ {
+			time.Sleep(20 * time.Millisecond)
+			s.DropConnections()
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			listener := func(*models.TickerNotification) {}
+			client.On("ticker.INSTR-1.100ms", listener)
+			s.Publish("ticker.INSTR-1.100ms", map[string]interface{}{"instrument_name": "INSTR-1"})
+			client.Off("ticker.INSTR-1.100ms", listener)
+		}
+	}()
+
+	wg.Wait()
+
+	// once the connection settles every requested channel is active again,
+	// ctx may have expired meanwhile on a loaded machine
+	settleCtx, settleCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer settleCancel()
+
+	require.Eventually(t, func() bool {
+		if !client.IsConnected() {
+			return false
+		}
+		client.subscribe(settleCtx)
+		return assert.ObjectsAreEqual(client.Subscriptions(), client.ActiveSubscriptions())
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestRace_ConcurrentStart(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, fastReconnect)
+
+	s.DropConnections()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = client.Start(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	require.Eventually(t, client.IsConnected, 2*time.Second, 10*time.Millisecond)
+
+	// Start and the reconnect routine share one dial loop, so there is a
+	// single live connection left
+	time.Sleep(100 * time.Millisecond)
+	_, err := client.GetTime()
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Accepted())
+}
+
+func TestRace_AuthAndPrivateCalls(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = client.Auth("key", "secret")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = client.isAuthenticated()
+			_, _ = client.SubscribeContext(context.Background(), []string{"user.orders.any.any.raw"})
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, client.isAuthenticated())
+	assert.Eventually(t, func() bool {
+		client.subscribe(context.Background())
+		return assert.ObjectsAreEqual([]string{"user.orders.any.any.raw"}, client.ActiveSubscriptions())
+	}, 2*time.Second, 10*time.Millisecond)
+}
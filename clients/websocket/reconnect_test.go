@@ -0,0 +1,197 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/sirupsen/logrus"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) listen(client *DeribitWSClient, events ...Event) {
+	for _, event := range events {
+		event := event
+		client.On(event, func(*websocketmodels.ConnectionEvent) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.events = append(r.events, event)
+		})
+	}
+}
+
+func (r *eventRecorder) has(event Event) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func fastReconnect(cfg *deribit.Configuration) {
+	cfg.AutoReconnect = true
+	cfg.ReconnectPolicy = &deribit.ExponentialBackoff{
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     50 * time.Millisecond,
+	}
+}
+
+func TestReconnect_LifecycleEvents(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, fastReconnect)
+
+	var rec eventRecorder
+	rec.listen(client, EventDisconnected, EventReconnecting, EventConnecting, EventConnected)
+
+	s.DropConnections()
+
+	assert.Eventually(t, func() bool {
+		return s.Accepted() == 2 && client.IsConnected() && rec.has(EventConnected)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	for _, event := range []Event{EventDisconnected, EventReconnecting, EventConnecting, EventConnected} {
+		assert.True(t, rec.has(event), event)
+	}
+}
+
+func TestReconnect_GaveUp(t *testing.T) {
+	var gaveUp sync.WaitGroup
+	gaveUp.Add(1)
+
+	cfg := &deribit.Configuration{
+		WebsocketConfiguration: &deribit.WebsocketConfiguration{
+			Url:                  "ws://127.0.0.1:1",
+			DialWebsocketTimeout: time.Second,
+			ReconnectPolicy: &deribit.ExponentialBackoff{
+				InitialInterval: time.Millisecond,
+				MaxAttempts:     2,
+				OnGiveUp: func(retries int, err error) {
+					assert.Equal(t, 2, retries)
+					assert.Error(t, err)
+					gaveUp.Done()
+				},
+			},
+		},
+		Client: &http.Client{},
+		Logger: logrus.New(),
+	}
+	client := NewDeribitWsClient(cfg)
+
+	var rec eventRecorder
+	rec.listen(client, EventConnecting, EventReconnecting, EventGaveUp)
+
+	err := client.Start(context.Background())
+	require.Error(t, err)
+	gaveUp.Wait()
+
+	assert.True(t, rec.has(EventGaveUp))
+	assert.False(t, client.IsConnected())
+}
+
+func TestReconnect_StartHonorsContext(t *testing.T) {
+	cfg := &deribit.Configuration{
+		WebsocketConfiguration: &deribit.WebsocketConfiguration{
+			Url:                  "ws://127.0.0.1:1",
+			DialWebsocketTimeout: time.Second,
+			ReconnectPolicy: &deribit.ExponentialBackoff{
+				InitialInterval: time.Hour,
+			},
+		},
+		Client: &http.Client{},
+		Logger: logrus.New(),
+	}
+	client := NewDeribitWsClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Start(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestReconnect_RetriesFailedSetup(t *testing.T) {
+	s := newMockServer(t)
+	var mu sync.Mutex
+	logins := 0
+	s.Handle("public/auth", func(raw json.RawMessage) (interface{}, error) {
+		var p struct {
+			GrantType string `json:"grant_type"`
+		}
+		_ = json.Unmarshal(raw, &p)
+
+		mu.Lock()
+		defer mu.Unlock()
+		// the session cannot be renewed, the credentials log in again
+		if p.GrantType == "refresh_token" {
+			return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeUnauthorized, Message: "invalid_token"}
+		}
+		logins++
+		if logins == 2 {
+			return nil, &jsonrpc2.Error{Code: 13004, Message: "invalid_credentials"}
+		}
+		return map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"expires_in":    900,
+			"scope":         fullAccessScope,
+		}, nil
+	})
+	client := newMockClient(t, s, fastReconnect, withCredential)
+
+	var rec eventRecorder
+	rec.listen(client, EventDisconnected, EventReconnecting)
+
+	s.DropConnections()
+
+	// the second connection fails auth, is closed and redialed
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return s.Accepted() == 3 && logins == 3 && client.IsConnected()
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, rec.has(EventReconnecting))
+
+	// the session of the third connection is fully set up
+	assert.Eventually(t, func() bool {
+		return s.Calls("public/set_heartbeat") == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err := client.GetTime()
+	assert.NoError(t, err)
+
+	// and it is watched, a drop reconnects again
+	s.DropConnections()
+	assert.Eventually(t, func() bool {
+		return s.Accepted() == 4 && client.IsConnected()
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReconnect_StartFailsSetup(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/auth", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: 13004, Message: "invalid_credentials"}
+	})
+
+	client := NewDeribitWsClient(mockConfig(s, withCredential))
+	err := client.Start(context.Background())
+	require.Error(t, err)
+	assert.False(t, client.IsConnected())
+	assert.Nil(t, client.getRPCConn())
+}
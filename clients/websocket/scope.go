@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+)
+
+// GrantedScopes returns the scope of the current access token, nil when the
+// connection is not authenticated
+func (c *DeribitWSClient) GrantedScopes() models.Scopes {
+	if auth := c.getAuthentication(); auth != nil {
+		return models.ParseScopes(auth.Scope)
+	}
+	return nil
+}
+
+// checkScope refuses a private method the granted scope does not allow before
+// it is sent, an area the granted scope does not mention allows no access.
+// Methods missing from the scope table of deribit.MethodScope, and calls
+// while no scope was granted, are left to the server.
+func (c *DeribitWSClient) checkScope(method string) error {
+	required, ok := deribit.MethodScope(method)
+	if !ok {
+		return nil
+	}
+
+	granted := c.GrantedScopes()
+	if len(granted) == 0 || granted.Allows(required) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s requires %s, granted %q", ErrScopeNotGranted, method, required, granted.String())
+}
@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/shopspring/decimal"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope_Enforced(t *testing.T) {
+	s := newMockServer(t)
+
+	var mu sync.Mutex
+	var requested string
+	s.Handle("public/auth", func(raw json.RawMessage) (interface{}, error) {
+		var p struct {
+			Scope string `json:"scope"`
+		}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+
+		mu.Lock()
+		requested = p.Scope
+		mu.Unlock()
+
+		return map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"expires_in":    900,
+			"scope":         "account:read trade:read wallet:none connection session:monitor",
+		}, nil
+	})
+	s.Handle("private/get_positions", func(json.RawMessage) (interface{}, error) {
+		return []interface{}{}, nil
+	})
+
+	client := newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.Credential = deribit.Credential{
+			ApiKey:    "key",
+			SecretKey: "secret",
+			Scope:     models.Scopes{models.SessionScope("monitor"), models.ScopeTradeRead, models.ScopeWalletNone},
+		}
+	})
+
+	mu.Lock()
+	assert.Equal(t, "session:monitor trade:read wallet:none", requested)
+	mu.Unlock()
+
+	granted := client.GrantedScopes()
+	session, ok := granted.Session()
+	assert.True(t, ok)
+	assert.Equal(t, "monitor", session)
+
+	_, err := client.GetPositionsContext(context.Background(), &models.GetPositionsParams{Currency: "BTC"})
+	require.NoError(t, err)
+
+	_, err = client.BuyContext(context.Background(), &models.BuyParams{InstrumentName: "BTC-PERPETUAL", Amount: decimal.NewFromInt(10)})
+	assert.True(t, errors.Is(err, ErrScopeNotGranted), err)
+	assert.Zero(t, s.Calls("private/buy"))
+
+	_, err = client.GetWithdrawalsContext(context.Background(), &models.GetWithdrawalsParams{Currency: "BTC"})
+	assert.True(t, errors.Is(err, ErrScopeNotGranted), err)
+	assert.Zero(t, s.Calls("private/get_withdrawals"))
+}
+
+func TestScope_Unknown(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/auth", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"access_token": "access-token", "expires_in": 900}, nil
+	})
+
+	client := newMockClient(t, s, withCredential)
+	assert.Nil(t, client.GrantedScopes())
+	assert.NoError(t, client.checkScope("private/buy"))
+
+	// methods missing from the scope table are left to the server
+	client.setAuthentication(&websocketmodels.Authentication{AccessToken: "access-token", Scope: "connection mainaccount"})
+	assert.NoError(t, client.checkScope("private/get_unknown"))
+}
+
+func TestScope_MissingArea(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, withCredential)
+
+	client.setAuthentication(&websocketmodels.Authentication{AccessToken: "access-token", Scope: "connection mainaccount trade:read"})
+	assert.NoError(t, client.checkScope("private/get_positions"))
+	// no wallet scope at all
+	assert.ErrorIs(t, client.checkScope("private/get_withdrawals"), ErrScopeNotGranted)
+	assert.ErrorIs(t, client.checkScope("private/withdraw"), ErrScopeNotGranted)
+}
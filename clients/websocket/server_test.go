@@ -0,0 +1,283 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/sirupsen/logrus"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// fullAccessScope is the scope granted by public/auth to a key with full
+// access
+const fullAccessScope = "account:read_write block_trade:read_write connection mainaccount trade:read_write wallet:read_write"
+
+// mockHandler answers one JSON-RPC method of the mock server. Returning a
+// *jsonrpc2.Error replies with that error object.
+type mockHandler func(params json.RawMessage) (interface{}, error)
+
+// mockServer is an in-process stand-in for the Deribit websocket API.
+type mockServer struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]mockHandler
+	calls    map[string]int
+	params   map[string]json.RawMessage
+	conns    []*websocket.Conn
+	accepted int
+}
+
+type mockRequest struct {
+	ID     *json.RawMessage `json:"id,omitempty"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params,omitempty"`
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	t.Helper()
+
+	s := &mockServer{
+		t:        t,
+		handlers: make(map[string]mockHandler),
+		calls:    make(map[string]int),
+		params:   make(map[string]json.RawMessage),
+	}
+
+	s.Handle("public/test", func(json.RawMessage) (interface{}, error) {
+		return map[string]string{"version": "1.2.26"}, nil
+	})
+	s.Handle("public/get_time", func(json.RawMessage) (interface{}, error) {
+		return time.Now().UnixMilli(), nil
+	})
+	s.Handle("public/set_heartbeat", okHandler)
+	s.Handle("public/disable_heartbeat", okHandler)
+	s.Handle("public/auth", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"expires_in":    900,
+			"scope":         fullAccessScope,
+			"token_type":    "bearer",
+		}, nil
+	})
+	s.Handle("public/subscribe", channelsHandler)
+	s.Handle("private/subscribe", channelsHandler)
+	s.Handle("public/unsubscribe", channelsHandler)
+	s.Handle("private/unsubscribe", channelsHandler)
+	s.Handle("public/unsubscribe_all", okHandler)
+	s.Handle("private/unsubscribe_all", okHandler)
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func okHandler(json.RawMessage) (interface{}, error) {
+	return "ok", nil
+}
+
+func channelsHandler(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Channels []string `json:"channels"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+	}
+	return p.Channels, nil
+}
+
+// URL returns the websocket url of the server
+func (s *mockServer) URL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+// Handle replaces the handler for method
+func (s *mockServer) Handle(method string, h mockHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Calls returns how many requests for method have been received
+func (s *mockServer) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// LastParams returns the params of the last request for method
+func (s *mockServer) LastParams(method string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.params[method]
+}
+
+// Accepted returns how many connections the server has accepted so far
+func (s *mockServer) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// Notify pushes a JSON-RPC notification to every connected client
+func (s *mockServer) Notify(method string, params interface{}) {
+	s.mu.Lock()
+	conns := append([]*websocket.Conn(nil), s.conns...)
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		msg := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  method,
+			"params":  params,
+		}
+		_ = wsjson.Write(context.Background(), conn, msg)
+	}
+}
+
+// Publish pushes a subscription notification for channel
+func (s *mockServer) Publish(channel string, data interface{}) {
+	s.Notify("subscription", map[string]interface{}{
+		"channel": channel,
+		"data":    data,
+	})
+}
+
+// DropConnections closes all client connections from the server side
+func (s *mockServer) DropConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.CloseNow()
+	}
+}
+
+// DropConnection closes the i-th live connection, in accept order
+func (s *mockServer) DropConnection(i int) {
+	s.mu.Lock()
+	conn := s.conns[i]
+	s.conns = append(s.conns[:i:i], s.conns[i+1:]...)
+	s.mu.Unlock()
+
+	_ = conn.CloseNow()
+}
+
+// Close drops every connection and stops the server
+func (s *mockServer) Close() {
+	s.DropConnections()
+	s.srv.Close()
+}
+
+func (s *mockServer) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.accepted++
+	s.mu.Unlock()
+
+	var writeMu sync.Mutex
+	write := func(v interface{}) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = wsjson.Write(context.Background(), conn, v)
+	}
+
+	for {
+		var req mockRequest
+		if err := wsjson.Read(context.Background(), conn, &req); err != nil {
+			_ = conn.CloseNow()
+			return
+		}
+		if req.ID == nil {
+			continue
+		}
+
+		s.mu.Lock()
+		s.calls[req.Method]++
+		s.params[req.Method] = req.Params
+		h := s.handlers[req.Method]
+		s.mu.Unlock()
+
+		// like Deribit, logout closes the connection without a response
+		if req.Method == "private/logout" {
+			_ = conn.CloseNow()
+			return
+		}
+
+		go func(req mockRequest) {
+			resp := map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req.ID,
+			}
+
+			if h == nil {
+				resp["error"] = &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method_not_found"}
+			} else if result, err := h(req.Params); err != nil {
+				if rpcErr, ok := err.(*jsonrpc2.Error); ok {
+					resp["error"] = rpcErr
+				} else {
+					resp["error"] = &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
+				}
+			} else {
+				resp["result"] = result
+			}
+
+			write(resp)
+		}(req)
+	}
+}
+
+// newMockClient returns a started client connected to s
+func newMockClient(t *testing.T, s *mockServer, modify ...func(*deribit.Configuration)) *DeribitWSClient {
+	t.Helper()
+
+	client := NewDeribitWsClient(mockConfig(s, modify...))
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close(context.Background())
+	})
+
+	return client
+}
+
+// mockConfig returns the configuration of a client of s
+func mockConfig(s *mockServer, modify ...func(*deribit.Configuration)) *deribit.Configuration {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg := &deribit.Configuration{
+		WebsocketConfiguration: &deribit.WebsocketConfiguration{
+			Url:                  s.URL(),
+			CallTimeout:          5 * time.Second,
+			DialWebsocketTimeout: 5 * time.Second,
+			HeartBeatInterval:    30,
+		},
+		Client: &http.Client{},
+		Logger: logger,
+	}
+	for _, m := range modify {
+		m(cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+)
+
+// ShutdownOptions selects the requests sent to the server before the
+// connection is closed by Shutdown
+type ShutdownOptions struct {
+	// CancelOpenOrders cancels all open orders with private/cancel_all
+	CancelOpenOrders bool
+	// Unsubscribe removes all public and private subscriptions
+	Unsubscribe bool
+	// DisableHeartbeat stops the server heartbeats
+	DisableHeartbeat bool
+	// Logout ends the authenticated session
+	Logout bool
+	// InvalidateToken revokes the tokens of the session on Logout
+	InvalidateToken bool
+}
+
+// DefaultShutdownOptions are used by Close, open orders are left untouched
+func DefaultShutdownOptions() ShutdownOptions {
+	return ShutdownOptions{
+		Unsubscribe:      true,
+		DisableHeartbeat: true,
+		Logout:           true,
+	}
+}
+
+// Close shuts the client down with DefaultShutdownOptions
+func (c *DeribitWSClient) Close(ctx context.Context) error {
+	return c.Shutdown(ctx, DefaultShutdownOptions())
+}
+
+// Shutdown sends the requests selected by opts, closes the connection and
+// waits until every goroutine of the client has exited or ctx is done.
+// The client does not reconnect afterwards and cannot be started again.
+// Errors of the shutdown requests are returned joined, they do not stop the
+// connection from being closed.
+func (c *DeribitWSClient) Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	c.spawnMu.Lock()
+	if c.shuttingDown {
+		c.spawnMu.Unlock()
+		return ErrClientClosed
+	}
+	c.shuttingDown = true
+	c.spawnMu.Unlock()
+
+	// first, a delivery to a Subscription nobody reads anymore would block
+	// the responses below
+	c.streams.closeAll()
+
+	var errs []error
+
+	if c.IsConnected() {
+		authenticated := c.isAuthenticated()
+
+		if opts.CancelOpenOrders && authenticated {
+			// the number of cancelled orders is not needed, CancelAllContext
+			// declares it as a string
+			var cancelled json.RawMessage
+			if err := c.CallContext(ctx, "private/cancel_all", nil, &cancelled); err != nil {
+				errs = append(errs, fmt.Errorf("cancel all: %w", err))
+			}
+		}
+
+		if opts.Unsubscribe {
+			if _, err := c.PublicUnsubscribeAllContext(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("public unsubscribe all: %w", err))
+			}
+			if authenticated {
+				if _, err := c.PrivateUnsubscribeAllContext(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("private unsubscribe all: %w", err))
+				}
+			}
+		}
+
+		if opts.DisableHeartbeat {
+			if _, err := c.DisableHeartbeatContext(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("disable heartbeat: %w", err))
+			}
+		}
+
+		if opts.Logout && authenticated {
+			if err := c.LogoutContext(ctx, &models.LogoutParams{InvalidateToken: opts.InvalidateToken}); err != nil {
+				errs = append(errs, fmt.Errorf("logout: %w", err))
+			}
+		}
+	}
+
+	// no goroutine may be started once closed is set, so wg.Wait below
+	// cannot race with wg.Add
+	c.spawnMu.Lock()
+	c.closed = true
+	c.spawnMu.Unlock()
+
+	c.stop()
+	c.setIsConnected(false)
+	// subscriptions added while the requests above were sent
+	c.streams.closeAll()
+
+	if rpcConn := c.getRPCConn(); rpcConn != nil {
+		// the server may already have closed the connection, e.g. after logout
+		if err := rpcConn.Close(); err != nil {
+			c.logger.WithContext(ctx).Debugln("close jsonrpc conn", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("wait goroutines: %w", ctx.Err()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// spawn runs f in a goroutine tracked by Shutdown, it returns false without
+// running f once the client is closed
+func (c *DeribitWSClient) spawn(f func()) bool {
+	c.spawnMu.Lock()
+	defer c.spawnMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		f()
+	}()
+
+	return true
+}
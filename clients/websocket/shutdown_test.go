@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// leakedGoroutines returns the stacks of goroutines still running client or
+// jsonrpc2 code
+func leakedGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+
+	var leaked []string
+	for _, g := range strings.Split(string(buf[:n]), "\n\n") {
+		if strings.Contains(g, "(*DeribitWSClient)") || strings.Contains(g, "jsonrpc2.(*Conn)") {
+			leaked = append(leaked, g)
+		}
+	}
+	return leaked
+}
+
+func assertNoLeaks(t *testing.T) {
+	t.Helper()
+
+	var leaked []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if leaked = leakedGoroutines(); len(leaked) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("leaked goroutines:\n%s", strings.Join(leaked, "\n\n"))
+}
+
+func TestClose_NoGoroutineLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	s := newMockServer(t)
+	client := newMockClient(t, s, fastReconnect, func(cfg *deribit.Configuration) {
+		cfg.Credential = deribit.Credential{ApiKey: "key", SecretKey: "secret"}
+		cfg.HeartBeatInterval = 0.05
+	})
+
+	s.Notify("heartbeat", map[string]string{"type": HeartbeatTypeTestRequest})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Close(ctx))
+	assertNoLeaks(t)
+
+	assert.Equal(t, 1, s.Calls("public/unsubscribe_all"))
+	assert.Equal(t, 1, s.Calls("private/unsubscribe_all"))
+	assert.Equal(t, 1, s.Calls("public/disable_heartbeat"))
+	assert.Equal(t, 0, s.Calls("private/cancel_all"))
+
+	// the client must not dial again after Close
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, s.Accepted())
+
+	s.Close()
+	// assert.Eventually runs its condition in a goroutine of its own, so
+	// poll by hand
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestShutdown_CancelOpenOrders(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("private/cancel_all", func(json.RawMessage) (interface{}, error) {
+		return 3, nil
+	})
+	client := newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.Credential = deribit.Credential{ApiKey: "key", SecretKey: "secret"}
+	})
+
+	err := client.Shutdown(context.Background(), ShutdownOptions{CancelOpenOrders: true})
+	require.NoError(t, err)
+	assertNoLeaks(t)
+
+	assert.Equal(t, 1, s.Calls("private/cancel_all"))
+	assert.Equal(t, 0, s.Calls("public/unsubscribe_all"))
+}
+
+func TestClose_AfterServerDrop(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, fastReconnect)
+
+	s.DropConnections()
+	assert.Eventually(t, func() bool {
+		return s.Accepted() == 2 && client.IsConnected()
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.Close(context.Background()))
+	assertNoLeaks(t)
+}
+
+func TestClose_StopsReconnectLoop(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, func(cfg *deribit.Configuration) {
+		cfg.AutoReconnect = true
+		cfg.ReconnectPolicy = &deribit.ExponentialBackoff{InitialInterval: time.Hour}
+	})
+
+	s.DropConnections()
+	assert.Eventually(t, func() bool {
+		return !client.IsConnected()
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.Close(context.Background()))
+	assertNoLeaks(t)
+
+	assert.ErrorIs(t, client.Start(context.Background()), ErrClientClosed)
+	_, err := client.GetTime()
+	assert.ErrorIs(t, err, ErrClientClosed)
+	assert.ErrorIs(t, client.Close(context.Background()), ErrClientClosed)
+}
+
+func TestShutdown_Concurrent(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/unsubscribe_all", func(json.RawMessage) (interface{}, error) {
+		// keeps the first Shutdown busy while the second one starts
+		time.Sleep(50 * time.Millisecond)
+		return "ok", nil
+	})
+	client := newMockClient(t, s)
+
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Close(context.Background())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var closed int
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrClientClosed)
+			closed++
+		}
+	}
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 1, s.Calls("public/unsubscribe_all"))
+	assertNoLeaks(t)
+}
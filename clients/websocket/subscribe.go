@@ -0,0 +1,320 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/models"
+)
+
+var ErrSubscriptionRejected = errors.New("websocket subscription rejected")
+
+// Subscribe adds channels to the subscriptions of the client and subscribes
+// them on the server, see SubscribeContext
+func (c *DeribitWSClient) Subscribe(channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return c.SubscribeContext(c.defaultContext(), channels)
+}
+
+// SubscribeContext adds channels to the subscriptions of the client, which
+// are renewed after every reconnect, and subscribes them on the server.
+// A result is returned per channel, in order. Channels are pending while the
+// client is not connected, and private channels while it is not authenticated.
+// Channels the server does not confirm are dropped from the subscriptions and
+// reported with ErrSubscriptionRejected.
+func (c *DeribitWSClient) SubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return c.subscribeAs(ctx, channels, true)
+}
+
+// subscribeStream is SubscribeContext for the typed streams, which keep
+// owning the channels they subscribed first
+func (c *DeribitWSClient) subscribeStream(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return c.subscribeAs(ctx, channels, false)
+}
+
+func (c *DeribitWSClient) subscribeAs(ctx context.Context, channels []string, explicit bool) ([]websocketmodels.SubscriptionResult, error) {
+	c.subsMu.Lock()
+	for _, channel := range channels {
+		c.subscriptions[channel] = struct{}{}
+	}
+	c.subsMu.Unlock()
+
+	if explicit {
+		// after the subscriptions are set, a Subscription registered
+		// meanwhile does not take ownership either
+		c.streams.disown(channels)
+	}
+
+	results := c.subscribeChannels(ctx, channels)
+	return results, subscriptionError(results)
+}
+
+// Unsubscribe removes channels from the subscriptions of the client, see
+// UnsubscribeContext
+func (c *DeribitWSClient) Unsubscribe(channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	return c.UnsubscribeContext(c.defaultContext(), channels)
+}
+
+// UnsubscribeContext removes channels from the subscriptions of the client,
+// so they are not renewed after a reconnect, and unsubscribes the active ones
+// on the server. A result is returned per channel, in order.
+func (c *DeribitWSClient) UnsubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error) {
+	results := make([]websocketmodels.SubscriptionResult, len(channels))
+	index := make(map[string]int, len(channels))
+
+	var publicChannels []string
+	var privateChannels []string
+
+	c.subsMu.Lock()
+	for i, channel := range channels {
+		results[i] = websocketmodels.SubscriptionResult{Channel: channel, Status: websocketmodels.SubscriptionConfirmed}
+		index[channel] = i
+
+		delete(c.subscriptions, channel)
+
+		if _, ok := c.subscriptionsMap[channel]; !ok {
+			continue
+		}
+		if !c.IsConnected() {
+			delete(c.subscriptionsMap, channel)
+			continue
+		}
+
+		if isPrivateChannel(channel) {
+			privateChannels = append(privateChannels, channel)
+		} else {
+			publicChannels = append(publicChannels, channel)
+		}
+	}
+	c.subsMu.Unlock()
+
+	apply := func(requested []string, response []string, err error) {
+		confirmed := toSet(response)
+
+		c.subsMu.Lock()
+		defer c.subsMu.Unlock()
+
+		for _, channel := range requested {
+			result := &results[index[channel]]
+			if err != nil {
+				// still active on the server, a later call may retry
+				result.Status, result.Err = websocketmodels.SubscriptionFailed, err
+				continue
+			}
+
+			delete(c.subscriptionsMap, channel)
+			if _, ok := confirmed[channel]; !ok {
+				result.Status, result.Err = websocketmodels.SubscriptionRejected, ErrSubscriptionRejected
+			}
+		}
+	}
+
+	c.callChunked(ctx, publicChannels, func(ctx context.Context, chunk []string) ([]string, error) {
+		return c.PublicUnsubscribeContext(ctx, &models.UnsubscribeParams{Channels: chunk})
+	}, apply)
+	c.callChunked(ctx, privateChannels, func(ctx context.Context, chunk []string) ([]string, error) {
+		return c.PrivateUnsubscribeContext(ctx, &models.UnsubscribeParams{Channels: chunk})
+	}, apply)
+
+	// the queues of the channels stop once the pending notifications are delivered
+	c.deliveries.release(channels)
+	c.cache.forget(channels)
+
+	return results, subscriptionError(results)
+}
+
+// Subscriptions returns the channels requested with Subscribe
+func (c *DeribitWSClient) Subscriptions() []string {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	return sortedKeys(c.subscriptions)
+}
+
+// ActiveSubscriptions returns the channels confirmed by the server on the
+// current connection
+func (c *DeribitWSClient) ActiveSubscriptions() []string {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	return sortedKeys(c.subscriptionsMap)
+}
+
+// subscribe subscribes every requested channel that is not active yet
+func (c *DeribitWSClient) subscribe(ctx context.Context) {
+	c.subsMu.Lock()
+	var channels []string
+	for channel := range c.subscriptions {
+		if _, ok := c.subscriptionsMap[channel]; !ok {
+			channels = append(channels, channel)
+		}
+	}
+	c.subsMu.Unlock()
+
+	if len(channels) == 0 {
+		return
+	}
+	sort.Strings(channels)
+
+	for _, result := range c.subscribeChannels(ctx, channels) {
+		switch result.Status {
+		case websocketmodels.SubscriptionRejected, websocketmodels.SubscriptionFailed:
+			c.logger.WithContext(ctx).Warnf("websocket subscribe %s %s: %v\n", result.Channel, result.Status, result.Err)
+		}
+	}
+}
+
+func (c *DeribitWSClient) subscribeChannels(ctx context.Context, channels []string) []websocketmodels.SubscriptionResult {
+	results := make([]websocketmodels.SubscriptionResult, len(channels))
+	index := make(map[string]int, len(channels))
+
+	connected := c.IsConnected()
+	authenticated := c.isAuthenticated()
+
+	var publicChannels []string
+	var privateChannels []string
+
+	c.subsMu.Lock()
+	gen := c.subscriptionsGen
+	for i, channel := range channels {
+		results[i] = websocketmodels.SubscriptionResult{Channel: channel, Status: websocketmodels.SubscriptionPending}
+
+		if _, ok := index[channel]; ok {
+			// duplicated in the request, filled in from the first one below
+			continue
+		}
+		index[channel] = i
+
+		if _, ok := c.subscriptionsMap[channel]; ok {
+			results[i].Status = websocketmodels.SubscriptionConfirmed
+			continue
+		}
+		if !connected {
+			continue
+		}
+
+		if isPrivateChannel(channel) {
+			if authenticated {
+				privateChannels = append(privateChannels, channel)
+			}
+		} else {
+			publicChannels = append(publicChannels, channel)
+		}
+	}
+	c.subsMu.Unlock()
+
+	apply := func(requested []string, response []string, err error) {
+		confirmed := toSet(response)
+
+		c.subsMu.Lock()
+		defer c.subsMu.Unlock()
+
+		for _, channel := range requested {
+			result := &results[index[channel]]
+			if err != nil {
+				result.Status, result.Err = websocketmodels.SubscriptionFailed, err
+				continue
+			}
+
+			if _, ok := confirmed[channel]; ok {
+				// confirmations from a connection lost meanwhile are renewed
+				// by the reconnect
+				if gen == c.subscriptionsGen {
+					c.subscriptionsMap[channel] = struct{}{}
+				}
+				result.Status = websocketmodels.SubscriptionConfirmed
+			} else {
+				delete(c.subscriptions, channel)
+				result.Status, result.Err = websocketmodels.SubscriptionRejected, ErrSubscriptionRejected
+			}
+		}
+	}
+
+	total := len(publicChannels) + len(privateChannels)
+	done := 0
+	applyChunk := func(requested []string, response []string, err error) {
+		apply(requested, response, err)
+
+		done += len(requested)
+		progress := websocketmodels.SubscribeProgress{Done: done, Total: total}
+		for _, channel := range requested {
+			progress.Results = append(progress.Results, results[index[channel]])
+		}
+		c.Emit(EventSubscribeProgress, &progress)
+	}
+
+	c.callChunked(ctx, publicChannels, func(ctx context.Context, chunk []string) ([]string, error) {
+		return c.PublicSubscribeContext(ctx, &models.SubscribeParams{Channels: chunk})
+	}, applyChunk)
+	c.callChunked(ctx, privateChannels, func(ctx context.Context, chunk []string) ([]string, error) {
+		return c.PrivateSubscribeContext(ctx, &models.SubscribeParams{Channels: chunk})
+	}, applyChunk)
+
+	for i, channel := range channels {
+		if first := index[channel]; first != i {
+			results[i] = results[first]
+		}
+	}
+
+	return results
+}
+
+func (c *DeribitWSClient) resetActiveSubscriptions() {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	c.subscriptionsMap = make(map[string]struct{})
+	c.subscriptionsGen++
+}
+
+func (c *DeribitWSClient) isAuthenticated() bool {
+	auth := c.getAuthentication()
+	return auth != nil && auth.AccessToken != ""
+}
+
+// subscriptionError joins the errors of failed and rejected results
+func subscriptionError(results []websocketmodels.SubscriptionResult) error {
+	var errs []error
+	var rejected []string
+
+	for _, result := range results {
+		switch result.Status {
+		case websocketmodels.SubscriptionRejected:
+			rejected = append(rejected, result.Channel)
+		case websocketmodels.SubscriptionFailed:
+			errs = append(errs, fmt.Errorf("%s: %w", result.Channel, result.Err))
+		}
+	}
+
+	if len(rejected) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrSubscriptionRejected, strings.Join(rejected, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// isPrivateChannel reports whether channel is subscribed with
+// private/subscribe, names that do not parse are private by their prefix
+func isPrivateChannel(channel string) bool {
+	if parsed, ok := parseChannel(channel); ok {
+		return parsed.Private()
+	}
+	return strings.HasPrefix(channel, "user.")
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+const defaultSubscribeChunkSize = 100
+
+// callChunked sends channels through call in requests of at most
+// SubscribeChunkSize channels and passes every chunk with its response to
+// apply, in order
+func (c *DeribitWSClient) callChunked(
+	ctx context.Context,
+	channels []string,
+	call func(ctx context.Context, chunk []string) ([]string, error),
+	apply func(chunk []string, response []string, err error),
+) {
+	size := c.cfg.SubscribeChunkSize
+	if size <= 0 {
+		size = defaultSubscribeChunkSize
+	}
+
+	for len(channels) > 0 {
+		n := min(size, len(channels))
+		chunk := channels[:n:n]
+		channels = channels[n:]
+
+		response, err := c.callPaced(ctx, chunk, call)
+		apply(chunk, response, err)
+	}
+}
+
+// callPaced waits for the subscribe rate limiter before every request and
+// retries requests rejected with too_many_requests following the subscribe
+// retry policy
+func (c *DeribitWSClient) callPaced(
+	ctx context.Context,
+	chunk []string,
+	call func(ctx context.Context, chunk []string) ([]string, error),
+) ([]string, error) {
+	policy := c.subscribeRetryPolicy()
+
+	for retry := 1; ; retry++ {
+		if err := c.subscribeLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		response, err := call(ctx, chunk)
+		if err == nil || !isTooManyRequestsError(err) {
+			return response, err
+		}
+
+		delay, ok := policy.NextDelay(retry)
+		if !ok {
+			policy.GiveUp(retry-1, err)
+			return nil, err
+		}
+		c.logger.WithContext(ctx).Warnf("websocket subscribe of %d channels rate limited, retry(%d) in %s\n", len(chunk), retry, delay)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-c.stopCtx.Done():
+			t.Stop()
+			return nil, ErrClientClosed
+		}
+	}
+}
+
+func (c *DeribitWSClient) subscribeRetryPolicy() deribit.ReconnectPolicy {
+	if c.cfg.SubscribeRetryPolicy != nil {
+		return c.cfg.SubscribeRetryPolicy
+	}
+	return deribit.DefaultSubscribeRetryPolicy()
+}
+
+func newSubscribeLimiter(cfg *deribit.WebsocketConfiguration) *deribit.RateLimiter {
+	if cfg.SubscribeRateLimit != nil {
+		return deribit.NewRateLimiter(*cfg.SubscribeRateLimit)
+	}
+	return deribit.NewRateLimiter(deribit.DefaultSubscribeRateLimit())
+}
+
+// isTooManyRequestsError reports whether the server rejected a request by
+// its rate limit
+func isTooManyRequestsError(err error) bool {
+	var rpcErr *jsonrpc2.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == deribit.ErrorCodeTooManyRequests
+}
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tickerChannels(n int) []string {
+	channels := make([]string, n)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("ticker.BTC-%d.100ms", i)
+	}
+	return channels
+}
+
+func withSubscribeLimits(chunkSize int, limit deribit.RateLimit) func(*deribit.Configuration) {
+	return func(cfg *deribit.Configuration) {
+		cfg.SubscribeChunkSize = chunkSize
+		cfg.SubscribeRateLimit = &limit
+		cfg.SubscribeRetryPolicy = &deribit.ExponentialBackoff{
+			InitialInterval: time.Millisecond,
+			MaxInterval:     5 * time.Millisecond,
+			MaxAttempts:     3,
+		}
+	}
+}
+
+func TestSubscribe_Chunked(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{}
+	s.Handle("public/subscribe", public.handler)
+	client := newMockClient(t, s, withSubscribeLimits(2, deribit.RateLimit{}))
+
+	var mu sync.Mutex
+	var progress []websocketmodels.SubscribeProgress
+	client.On(EventSubscribeProgress, func(p *websocketmodels.SubscribeProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress = append(progress, *p)
+	})
+
+	channels := tickerChannels(5)
+	results, err := client.Subscribe(channels)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.Equal(t, [][]string{channels[:2], channels[2:4], channels[4:]}, public.Requests())
+	assert.Equal(t, channels, client.ActiveSubscriptions())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, progress, 3)
+	for i, done := range []int{2, 4, 5} {
+		assert.Equal(t, done, progress[i].Done)
+		assert.Equal(t, 5, progress[i].Total)
+	}
+	assert.Equal(t, channels[4], progress[2].Results[0].Channel)
+	assert.Equal(t, websocketmodels.SubscriptionConfirmed, progress[2].Results[0].Status)
+}
+
+func TestSubscribe_RetryTooManyRequests(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{}
+	var limited atomic.Int32
+	s.Handle("public/subscribe", func(params json.RawMessage) (interface{}, error) {
+		if limited.Add(1) <= 2 {
+			return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeTooManyRequests, Message: "too_many_requests"}
+		}
+		return public.handler(params)
+	})
+	client := newMockClient(t, s, withSubscribeLimits(10, deribit.RateLimit{}))
+
+	results, err := client.Subscribe(tickerChannels(3))
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, websocketmodels.SubscriptionConfirmed, result.Status)
+	}
+	assert.Equal(t, 3, s.Calls("public/subscribe"))
+}
+
+func TestSubscribe_TooManyRequestsGiveUp(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/subscribe", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeTooManyRequests, Message: "too_many_requests"}
+	})
+	client := newMockClient(t, s, withSubscribeLimits(2, deribit.RateLimit{}))
+
+	results, err := client.Subscribe(tickerChannels(3))
+	require.Error(t, err)
+	for _, result := range results {
+		assert.Equal(t, websocketmodels.SubscriptionFailed, result.Status)
+		assert.True(t, isTooManyRequestsError(result.Err), result.Err)
+	}
+	// the initial request and 3 retries per chunk
+	assert.Equal(t, 8, s.Calls("public/subscribe"))
+}
+
+func TestSubscribe_Paced(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, withSubscribeLimits(1, deribit.RateLimit{Rate: 50, Burst: 1}))
+
+	// the connection setup may have used the single token
+	time.Sleep(30 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.SubscribeContext(context.Background(), tickerChannels(4))
+	require.NoError(t, err)
+
+	// one request immediately, three 20ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 55*time.Millisecond)
+	assert.Equal(t, 4, s.Calls("public/subscribe"))
+}
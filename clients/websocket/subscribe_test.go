@@ -0,0 +1,176 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// channelRecorder records the channels of every request of a method and
+// confirms all of them except the rejected ones
+type channelRecorder struct {
+	mu       sync.Mutex
+	requests [][]string
+	rejected map[string]bool
+}
+
+func (r *channelRecorder) handler(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Channels []string `json:"channels"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, p.Channels)
+
+	confirmed := []string{}
+	for _, channel := range p.Channels {
+		if !r.rejected[channel] {
+			confirmed = append(confirmed, channel)
+		}
+	}
+	return confirmed, nil
+}
+
+func (r *channelRecorder) Requests() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.requests...)
+}
+
+func TestSubscribe_Confirmed(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{}
+	s.Handle("public/subscribe", public.handler)
+	client := newMockClient(t, s)
+
+	results, err := client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms", "trades.BTC-PERPETUAL.raw"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, websocketmodels.SubscriptionConfirmed, result.Status, result.Channel)
+	}
+
+	// already active channels are not requested again
+	results, err = client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms"})
+	require.NoError(t, err)
+	assert.Equal(t, websocketmodels.SubscriptionConfirmed, results[0].Status)
+	assert.Len(t, public.Requests(), 1)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms", "trades.BTC-PERPETUAL.raw"}, client.ActiveSubscriptions())
+}
+
+func TestSubscribe_Rejected(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{rejected: map[string]bool{"ticker.NOPE.100ms": true}}
+	s.Handle("public/subscribe", public.handler)
+	client := newMockClient(t, s, fastReconnect)
+
+	results, err := client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms", "ticker.NOPE.100ms"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSubscriptionRejected))
+	assert.Equal(t, websocketmodels.SubscriptionConfirmed, results[0].Status)
+	assert.Equal(t, websocketmodels.SubscriptionRejected, results[1].Status)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, client.Subscriptions())
+
+	// rejected channels are not renewed after a reconnect
+	s.DropConnections()
+	assert.Eventually(t, func() bool {
+		return len(public.Requests()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, public.Requests()[1])
+}
+
+func TestSubscribe_RequestFailed(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("public/subscribe", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: 10028, Message: "too_many_requests"}
+	})
+	// rate limited requests are retried before failing
+	client := newMockClient(t, s, withSubscribeLimits(0, deribit.RateLimit{}))
+
+	results, err := client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms"})
+	require.Error(t, err)
+
+	var rpcErr *jsonrpc2.Error
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, int64(10028), rpcErr.Code)
+	assert.Equal(t, websocketmodels.SubscriptionFailed, results[0].Status)
+
+	// failed channels stay requested and are retried
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, client.Subscriptions())
+	assert.Empty(t, client.ActiveSubscriptions())
+}
+
+func TestSubscribe_PrivateQueuedUntilAuth(t *testing.T) {
+	s := newMockServer(t)
+	private := &channelRecorder{}
+	s.Handle("private/subscribe", private.handler)
+	client := newMockClient(t, s)
+
+	results, err := client.Subscribe([]string{"user.orders.BTC-PERPETUAL.raw"})
+	require.NoError(t, err)
+	assert.Equal(t, websocketmodels.SubscriptionPending, results[0].Status)
+	assert.Empty(t, private.Requests())
+
+	require.NoError(t, client.AuthContext(context.Background(), "key", "secret"))
+
+	require.Len(t, private.Requests(), 1)
+	assert.Equal(t, []string{"user.orders.BTC-PERPETUAL.raw"}, private.Requests()[0])
+	assert.Equal(t, []string{"user.orders.BTC-PERPETUAL.raw"}, client.ActiveSubscriptions())
+}
+
+func TestSubscribe_PendingUntilConnected(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{}
+	s.Handle("public/subscribe", public.handler)
+
+	client := newMockClient(t, s)
+	client.setIsConnected(false)
+
+	results, err := client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms"})
+	require.NoError(t, err)
+	assert.Equal(t, websocketmodels.SubscriptionPending, results[0].Status)
+	assert.Empty(t, public.Requests())
+}
+
+func TestUnsubscribe(t *testing.T) {
+	s := newMockServer(t)
+	public := &channelRecorder{}
+	unsubscribe := &channelRecorder{}
+	s.Handle("public/subscribe", public.handler)
+	s.Handle("public/unsubscribe", unsubscribe.handler)
+	client := newMockClient(t, s, fastReconnect)
+
+	_, err := client.Subscribe([]string{"ticker.BTC-PERPETUAL.100ms", "trades.BTC-PERPETUAL.raw"})
+	require.NoError(t, err)
+
+	results, err := client.Unsubscribe([]string{"trades.BTC-PERPETUAL.raw", "quote.ETH-PERPETUAL"})
+	require.NoError(t, err)
+	assert.Equal(t, websocketmodels.SubscriptionConfirmed, results[0].Status)
+	assert.Equal(t, websocketmodels.SubscriptionConfirmed, results[1].Status)
+
+	// only active channels are sent to the server
+	require.Len(t, unsubscribe.Requests(), 1)
+	assert.Equal(t, []string{"trades.BTC-PERPETUAL.raw"}, unsubscribe.Requests()[0])
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, client.Subscriptions())
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, client.ActiveSubscriptions())
+
+	// removed channels are not renewed after a reconnect
+	s.DropConnections()
+	assert.Eventually(t, func() bool {
+		return len(public.Requests()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ticker.BTC-PERPETUAL.100ms"}, public.Requests()[1])
+}
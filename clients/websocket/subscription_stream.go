@@ -0,0 +1,502 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/chuckpreslar/emission"
+)
+
+// ErrUnexpectedType is sent on the error channel of a Subscription when a
+// notification does not have the type of the subscription
+var ErrUnexpectedType = errors.New("websocket notification of unexpected type")
+
+const (
+	defaultStreamBuffer      = 128
+	defaultStreamErrorBuffer = 16
+)
+
+// Subscriber is a DeribitWSClient or a Pool, the source of the typed streams
+// of Subscribe and Listen
+type Subscriber interface {
+	SubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error)
+	UnsubscribeContext(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error)
+	CallContext(ctx context.Context, method string, params interface{}, result interface{}) error
+	Emit(event interface{}, arguments ...interface{}) *emission.Emitter
+
+	subscribeStream(ctx context.Context, channels []string) ([]websocketmodels.SubscriptionResult, error)
+	defaultContext() context.Context
+	streamRegistry() *streamRegistry
+	notificationCache() *notificationCache
+	isClosed() bool
+	isSubscribed(channel string) bool
+	spawn(f func()) bool
+}
+
+// Subscription is a typed stream of the notifications of one channel,
+// returned by Subscribe. Close it when done, the channel is unsubscribed on
+// the server once its last Subscription is closed.
+type Subscription[T any] struct {
+	*stream[T]
+
+	client  Subscriber
+	channel string
+	id      uint64
+	once    sync.Once
+	// decode, if set, decodes the data of notifications instead of taking
+	// the value of the decoder of the client
+	decode func(data json.RawMessage) (T, error)
+}
+
+// Subscribe subscribes channel on the server and returns a Subscription
+// delivering its notifications as T, e.g.
+//
+//	sub, err := Subscribe[*models.OrderBookRawNotification](ctx, client, "book.BTC-PERPETUAL.raw")
+//
+// A notification that is neither a T nor a pointer to T is reported on Err.
+// A cached notification of the channel is delivered first. The channel is
+// renewed after reconnects like the channels of
+// DeribitWSClient.Subscribe. c is a DeribitWSClient or a Pool.
+func Subscribe[T any](ctx context.Context, c Subscriber, channel string) (*Subscription[T], error) {
+	s, err := newSubscription[T](c, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.subscribeStream(ctx, []string{channel}); err != nil {
+		_ = s.CloseContext(ctx)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newSubscription registers a Subscription of channel, the caller subscribes
+// the channel on the server
+func newSubscription[T any](c Subscriber, channel string) (*Subscription[T], error) {
+	return newDecodingSubscription[T](c, channel, nil)
+}
+
+// newDecodingSubscription is newSubscription decoding the data of the
+// notifications with decode. Cached notifications have no data and are
+// delivered as decoded by the client.
+func newDecodingSubscription[T any](c Subscriber, channel string, decode func(data json.RawMessage) (T, error)) (*Subscription[T], error) {
+	s := &Subscription[T]{
+		stream:  newStream[T](),
+		client:  c,
+		channel: channel,
+		decode:  decode,
+	}
+
+	var err error
+	c.notificationCache().withStreamReplay(channel, func() {
+		err = c.streamRegistry().add(c, channel, s)
+	}, func(channel string, notification interface{}) {
+		if err == nil {
+			s.deliver(channel, notification)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Channel returns the subscribed channel name
+func (s *Subscription[T]) Channel() string {
+	return s.channel
+}
+
+// Close closes the Subscription, see CloseContext
+func (s *Subscription[T]) Close() error {
+	return s.CloseContext(s.client.defaultContext())
+}
+
+// CloseContext stops the delivery and closes C and Err. The channel is
+// unsubscribed on the server when no other Subscription uses it and it was
+// not subscribed with the Subscribe method of the client.
+func (s *Subscription[T]) CloseContext(ctx context.Context) (err error) {
+	if s.release() {
+		_, err = s.client.UnsubscribeContext(ctx, []string{s.channel})
+	}
+	return
+}
+
+// release stops the delivery once and tells whether the caller has to
+// unsubscribe the channel on the server
+func (s *Subscription[T]) release() (unsubscribe bool) {
+	s.once.Do(func() {
+		s.closeLocal()
+		unsubscribe = s.client.streamRegistry().remove(s.channel, s.id)
+	})
+	return
+}
+
+func (s *Subscription[T]) setID(id uint64) {
+	s.id = id
+}
+
+func (s *Subscription[T]) deliver(_ string, v interface{}) {
+	value, err := convertNotification[T](s.channel, v)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	s.send(value)
+}
+
+func (s *Subscription[T]) deliverData(_ string, data json.RawMessage) bool {
+	if s.decode == nil {
+		return false
+	}
+
+	value, err := s.decode(data)
+	if err != nil {
+		s.fail(&DecodeError{Channel: s.channel, Data: data, Err: err})
+		return true
+	}
+	s.send(value)
+	return true
+}
+
+// stream holds the channels of a Subscription or Listener
+type stream[T any] struct {
+	data chan T
+	errs chan error
+
+	// mu guards sends against closing data and errs
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newStream[T any]() *stream[T] {
+	return &stream[T]{
+		data: make(chan T, defaultStreamBuffer),
+		errs: make(chan error, defaultStreamErrorBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+// C returns the notifications, it is closed by Close
+func (s *stream[T]) C() <-chan T {
+	return s.data
+}
+
+// Err returns the errors of the stream, it is closed by Close
+func (s *stream[T]) Err() <-chan error {
+	return s.errs
+}
+
+// Done is closed once the stream is closed
+func (s *stream[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// closeLocal closes the channels of the stream without unsubscribing
+func (s *stream[T]) closeLocal() {
+	s.closeOnce.Do(func() {
+		// wakes a blocked send before taking the write lock
+		close(s.done)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.data)
+		close(s.errs)
+	})
+}
+
+// send sends v on C, blocking while C is full
+func (s *stream[T]) send(v T) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.data <- v:
+	case <-s.done:
+	}
+}
+
+// fail sends err on Err, errors are dropped while Err is full
+func (s *stream[T]) fail(err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// convertNotification returns v as T, v may be a T or a non-nil *T
+func convertNotification[T any](channel string, v interface{}) (T, error) {
+	if value, ok := v.(T); ok {
+		return value, nil
+	}
+	if p, ok := v.(*T); ok && p != nil {
+		return *p, nil
+	}
+
+	var want T
+	return want, fmt.Errorf("%w: channel %s delivered %T, want %T", ErrUnexpectedType, channel, v, want)
+}
+
+func (c *DeribitWSClient) streamRegistry() *streamRegistry {
+	return c.streams
+}
+
+func (c *DeribitWSClient) notificationCache() *notificationCache {
+	return c.cache
+}
+
+func (c *DeribitWSClient) isClosed() bool {
+	return c.stopCtx.Err() != nil
+}
+
+func (c *DeribitWSClient) isSubscribed(channel string) bool {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	_, ok := c.subscriptions[channel]
+	return ok
+}
+
+// streamSink is the untyped side of a Subscription or Listener
+type streamSink interface {
+	setID(id uint64)
+	deliver(channel string, v interface{})
+	fail(err error)
+	closeLocal()
+}
+
+// dataSink is a streamSink that may decode the data of a notification itself
+type dataSink interface {
+	// deliverData reports false when the sink takes the decoded notification
+	deliverData(channel string, data json.RawMessage) bool
+}
+
+// streamRegistry holds the Subscriptions of a client by channel and its
+// Listeners. It replaces the emitter for typed streams, whose listeners cannot
+// be told apart by emission.
+type streamRegistry struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	channels map[string]*streamChannel
+	matchers map[uint64]streamMatcher
+	// locals are the streams not bound to a channel, e.g. of a BookManager,
+	// closed with the others on shutdown
+	locals map[uint64]localStream
+}
+
+// localStream is a stream the registry only closes
+type localStream interface {
+	closeLocal()
+}
+
+type streamChannel struct {
+	sinks map[uint64]streamSink
+	// owned is set when the first Subscription subscribed the channel, so the
+	// last one may unsubscribe it, and cleared when the Subscribe method of
+	// the client subscribes it as well
+	owned bool
+}
+
+type streamMatcher struct {
+	matcher ChannelMatcher
+	sink    streamSink
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{
+		channels: make(map[string]*streamChannel),
+		matchers: make(map[uint64]streamMatcher),
+		locals:   make(map[uint64]localStream),
+	}
+}
+
+// add registers sink for channel and assigns its id
+func (r *streamRegistry) add(c Subscriber, channel string, sink streamSink) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch, ok := r.channels[channel]
+	if !ok {
+		ch = &streamChannel{sinks: make(map[uint64]streamSink), owned: !c.isSubscribed(channel)}
+		r.channels[channel] = ch
+	}
+
+	r.nextID++
+	sink.setID(r.nextID)
+	ch.sinks[r.nextID] = sink
+	return nil
+}
+
+// remove drops the sink id of channel and reports whether the channel should
+// be unsubscribed
+func (r *streamRegistry) remove(channel string, id uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch, ok := r.channels[channel]
+	if !ok {
+		return false
+	}
+	delete(ch.sinks, id)
+	if len(ch.sinks) > 0 {
+		return false
+	}
+
+	delete(r.channels, channel)
+	return ch.owned
+}
+
+// disown clears the ownership of channels subscribed with the Subscribe
+// method of the client, their last Subscription keeps them subscribed
+func (r *streamRegistry) disown(channels []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, channel := range channels {
+		if ch, ok := r.channels[channel]; ok {
+			ch.owned = false
+		}
+	}
+}
+
+// exclusive tells whether the sink id is the only consumer of channel, which
+// may then be resubscribed without disturbing others
+func (r *streamRegistry) exclusive(channel string, id uint64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ch, ok := r.channels[channel]
+	if !ok || !ch.owned || len(ch.sinks) != 1 {
+		return false
+	}
+	if _, ok := ch.sinks[id]; !ok {
+		return false
+	}
+	for _, m := range r.matchers {
+		if m.matcher.Match(channel) {
+			return false
+		}
+	}
+	return true
+}
+
+// addMatcher registers sink for the channels matched by matcher and assigns
+// its id
+func (r *streamRegistry) addMatcher(c Subscriber, matcher ChannelMatcher, sink streamSink) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	sink.setID(r.nextID)
+	r.matchers[r.nextID] = streamMatcher{matcher: matcher, sink: sink}
+	return nil
+}
+
+func (r *streamRegistry) removeMatcher(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.matchers, id)
+}
+
+// addLocal registers s to be closed on shutdown and returns its id, s is
+// closed right away when the client is closed
+func (r *streamRegistry) addLocal(c Subscriber, s localStream) uint64 {
+	if c.isClosed() {
+		s.closeLocal()
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	r.locals[r.nextID] = s
+	return r.nextID
+}
+
+func (r *streamRegistry) removeLocal(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.locals, id)
+}
+
+// deliverSinks passes v to sinks, the Subscriptions and Listeners of channel,
+// or data, the JSON v was decoded from, to those decoding it themselves
+func deliverSinks(sinks []streamSink, channel string, v interface{}, data json.RawMessage) {
+	for _, sink := range sinks {
+		if d, ok := sink.(dataSink); ok && data != nil && d.deliverData(channel, data) {
+			continue
+		}
+		sink.deliver(channel, v)
+	}
+}
+
+// fail passes err to every Subscription and Listener of channel
+func (r *streamRegistry) fail(channel string, err error) {
+	for _, sink := range r.sinks(channel) {
+		sink.fail(err)
+	}
+}
+
+func (r *streamRegistry) sinks(channel string) []streamSink {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sinks []streamSink
+	if ch, ok := r.channels[channel]; ok {
+		for _, sink := range ch.sinks {
+			sinks = append(sinks, sink)
+		}
+	}
+	for _, m := range r.matchers {
+		if m.matcher.Match(channel) {
+			sinks = append(sinks, m.sink)
+		}
+	}
+	return sinks
+}
+
+// closeAll closes every Subscription and Listener without unsubscribing, used on shutdown
+func (r *streamRegistry) closeAll() {
+	r.mu.Lock()
+	channels, matchers, locals := r.channels, r.matchers, r.locals
+	r.channels = make(map[string]*streamChannel)
+	r.matchers = make(map[uint64]streamMatcher)
+	r.locals = make(map[uint64]localStream)
+	r.mu.Unlock()
+
+	for _, ch := range channels {
+		for _, sink := range ch.sinks {
+			sink.closeLocal()
+		}
+	}
+	for _, m := range matchers {
+		m.sink.closeLocal()
+	}
+	for _, s := range locals {
+		s.closeLocal()
+	}
+}
@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tickerChannel = "ticker.BTC-PERPETUAL.100ms"
+
+func receive[T any](t *testing.T, c <-chan T) T {
+	t.Helper()
+
+	select {
+	case v, ok := <-c:
+		require.True(t, ok, "channel closed")
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for notification")
+	}
+
+	var zero T
+	return zero
+}
+
+func TestSubscription_Typed(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	ctx := context.Background()
+
+	byPointer, err := Subscribe[*models.TickerNotification](ctx, client, tickerChannel)
+	require.NoError(t, err)
+	defer byPointer.Close()
+
+	byValue, err := Subscribe[models.TickerNotification](ctx, client, tickerChannel)
+	require.NoError(t, err)
+	defer byValue.Close()
+
+	assert.Equal(t, tickerChannel, byPointer.Channel())
+	assert.Equal(t, []string{tickerChannel}, client.ActiveSubscriptions())
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1700000000000, "state": "open"})
+
+	p := receive(t, byPointer.C())
+	assert.Equal(t, int64(1700000000000), p.Timestamp)
+
+	v := receive(t, byValue.C())
+	assert.Equal(t, "open", v.State)
+}
+
+func TestSubscription_UnexpectedType(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	sub, err := Subscribe[*models.TradesNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 1})
+
+	err = receive(t, sub.Err())
+	assert.True(t, errors.Is(err, ErrUnexpectedType), err)
+}
+
+func TestSubscription_DecodeError(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": "not a number"})
+
+	err = receive(t, sub.Err())
+	assert.Contains(t, err.Error(), tickerChannel)
+}
+
+func TestSubscription_CloseUnsubscribesLast(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+	ctx := context.Background()
+
+	first, err := Subscribe[*models.TickerNotification](ctx, client, tickerChannel)
+	require.NoError(t, err)
+	second, err := Subscribe[*models.TickerNotification](ctx, client, tickerChannel)
+	require.NoError(t, err)
+
+	require.NoError(t, first.Close())
+	_, ok := <-first.C()
+	assert.False(t, ok)
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+	assert.Equal(t, []string{tickerChannel}, client.Subscriptions())
+
+	// closing twice is a no-op
+	require.NoError(t, first.Close())
+
+	s.Publish(tickerChannel, map[string]interface{}{"timestamp": 2})
+	assert.Equal(t, int64(2), receive(t, second.C()).Timestamp)
+
+	require.NoError(t, second.Close())
+	assert.Equal(t, 1, s.Calls("public/unsubscribe"))
+	assert.Empty(t, client.Subscriptions())
+	assert.Empty(t, client.ActiveSubscriptions())
+}
+
+func TestSubscription_KeepsClientSubscription(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	_, err := client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+	assert.Equal(t, []string{tickerChannel}, client.ActiveSubscriptions())
+}
+
+func TestSubscription_KeepsLaterClientSubscription(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	_, err = client.Subscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+
+	assert.Zero(t, s.Calls("public/unsubscribe"))
+	assert.Equal(t, []string{tickerChannel}, client.ActiveSubscriptions())
+
+	// owned again by a new first Subscription once the client unsubscribed
+	_, err = client.Unsubscribe([]string{tickerChannel})
+	require.NoError(t, err)
+	sub, err = Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+	assert.Equal(t, 2, s.Calls("public/unsubscribe"))
+	assert.Empty(t, client.ActiveSubscriptions())
+}
+
+func TestSubscription_Rejected(t *testing.T) {
+	s := newMockServer(t)
+	rec := &channelRecorder{rejected: map[string]bool{tickerChannel: true}}
+	s.Handle("public/subscribe", rec.handler)
+	client := newMockClient(t, s)
+
+	_, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	assert.True(t, errors.Is(err, ErrSubscriptionRejected), err)
+	assert.Empty(t, client.streams.sinks(tickerChannel))
+}
+
+func TestSubscription_ClosedOnShutdown(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s)
+
+	sub, err := Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	require.NoError(t, err)
+
+	// nobody reads the subscription, its delivery blocks once it is full
+	for i := 0; i < defaultStreamBuffer+1; i++ {
+		s.Publish(tickerChannel, map[string]interface{}{"timestamp": i})
+	}
+
+	require.NoError(t, client.Close(context.Background()))
+
+	select {
+	case <-sub.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription not closed")
+	}
+
+	_, err = Subscribe[*models.TickerNotification](context.Background(), client, tickerChannel)
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
@@ -1,7 +1,5 @@
 package websocket
 
-import "fmt"
-
 const (
 	CHANNEL_USER_ACCESS_LOG                  = "user.access_log"
 	CHANNEL_USER_MMP_TRIGGER_PATTERN         = "user.mmp_trigger.%s"         //user.mmp_trigger.{index_name}
@@ -18,7 +16,7 @@ func ChannelUserMMPTrigger(idxname string) string {
 	if idxname == "" {
 		return ""
 	}
-	return fmt.Sprintf(CHANNEL_USER_MMP_TRIGGER_PATTERN, idxname)
+	return UserMMPTriggerChannel(idxname).String()
 }
 
 func ChannelBookGroup(instrumentname, group string, depth int, interval string) string {
@@ -38,7 +36,7 @@ func ChannelBookGroup(instrumentname, group string, depth int, interval string)
 		interval = "100ms"
 	}
 
-	return fmt.Sprintf(CHANNEL_BOOK_GROUP_PATTERN, instrumentname, group, depth, interval)
+	return BookGroupChannel(instrumentname, group, depth, interval).String()
 }
 
 func ChannelDeribitVolatilityIndex(idxname string) string {
@@ -46,5 +44,5 @@ func ChannelDeribitVolatilityIndex(idxname string) string {
 		return ""
 	}
 
-	return fmt.Sprintf(CHANNEL_DERIBIT_VOLATILITY_INDEX_PATTERN, idxname)
+	return VolatilityIndexChannel(idxname).String()
 }
@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	websocketmodels "github.com/BestNathan/deribit-api/clients/websocket/models"
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// refreshRetryInterval is the wait after a failed scheduled token refresh
+const refreshRetryInterval = 10 * time.Second
+
+// authenticate runs on every new connection. The refresh token of the
+// previous connection renews the session first, keeping its subaccount and
+// session name, otherwise the configured credentials log in again, see login.
+// A session that cannot be renewed without credentials is dropped, the
+// connection stays usable for public methods.
+func (c *DeribitWSClient) authenticate(ctx context.Context) error {
+	if c.loggedOut.Load() {
+		return nil
+	}
+
+	if auth := c.getAuthentication(); auth != nil && auth.RefreshToken != "" {
+		err := c.refreshToken(ctx, auth.RefreshToken)
+		if err == nil {
+			c.emitConnectionEvent(EventAuthenticated, websocketmodels.ConnectionEvent{})
+			return nil
+		}
+		c.logger.WithContext(ctx).Warnln("re-authenticate with refresh token fail", err)
+		c.setAuthentication(nil)
+	}
+
+	if !c.hasCredential() {
+		return nil
+	}
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	c.emitConnectionEvent(EventAuthenticated, websocketmodels.ConnectionEvent{})
+	return nil
+}
+
+// sessionSwitch is an ExchangeToken or ForkToken call, params returns its
+// params for the refresh token of the session it is applied to
+type sessionSwitch struct {
+	method string
+	params func(refreshToken string) interface{}
+}
+
+// login authenticates with the configured credentials and switches to the
+// subaccount and the named session of the last ExchangeToken and ForkToken
+// calls. The session is dropped if a switch fails, so that private calls
+// never reach the main account instead.
+func (c *DeribitWSClient) login(ctx context.Context) error {
+	if err := c.auth(ctx, c.credential.ApiKey, c.credential.SecretKey, c.credential.Scope); err != nil {
+		return err
+	}
+
+	c.authMu.RLock()
+	switches := c.switches
+	c.authMu.RUnlock()
+
+	for _, s := range switches {
+		refreshToken, err := c.currentRefreshToken()
+		if err == nil {
+			_, err = c.authCall(ctx, s.method, s.params(refreshToken))
+		}
+		if err != nil {
+			c.setAuthentication(nil)
+			return fmt.Errorf("%s after login: %w", s.method, err)
+		}
+	}
+	return nil
+}
+
+// setSwitches replaces the switches applied after a login
+func (c *DeribitWSClient) setSwitches(switches []sessionSwitch) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.switches = switches
+}
+
+// addSwitch records s to be applied after a login, an ExchangeToken replaces
+// the earlier switches as it selects the account on its own
+func (c *DeribitWSClient) addSwitch(s sessionSwitch) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if s.method == "public/exchange_token" {
+		c.switches = nil
+	}
+	c.switches = append(append([]sessionSwitch(nil), c.switches...), s)
+}
+
+// refreshAuthentication renews the access token, with the refresh token if
+// there is one and with the configured credentials otherwise, see login. If failedToken
+// is set and the current access token differs, another caller has already
+// refreshed it and nothing is done.
+func (c *DeribitWSClient) refreshAuthentication(ctx context.Context, failedToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if c.loggedOut.Load() {
+		return ErrUnAuthorized
+	}
+
+	auth := c.getAuthentication()
+	if failedToken != "" && auth != nil && auth.AccessToken != failedToken {
+		return nil
+	}
+
+	var errs []error
+	if auth != nil && auth.RefreshToken != "" {
+		err := c.refreshToken(ctx, auth.RefreshToken)
+		if err == nil {
+			c.emitConnectionEvent(EventTokenRefreshed, websocketmodels.ConnectionEvent{})
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	if c.hasCredential() {
+		err := c.login(ctx)
+		if err == nil {
+			c.emitConnectionEvent(EventTokenRefreshed, websocketmodels.ConnectionEvent{})
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return ErrUnAuthorized
+	}
+	return errors.Join(errs...)
+}
+
+func (c *DeribitWSClient) refreshToken(ctx context.Context, refreshToken string) error {
+	return c.authWith(ctx, models.RefreshTokenParams{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+	})
+}
+
+// refreshTokenLoop refreshes the access token of rpcConn before it expires,
+// it exits when the connection is gone
+func (c *DeribitWSClient) refreshTokenLoop(ctx context.Context, rpcConn *jsonrpc2.Conn) {
+	for {
+		if !c.waitRefresh(rpcConn) {
+			return
+		}
+
+		if err := c.refreshAuthentication(ctx, ""); err != nil {
+			c.logger.WithContext(ctx).Warnln("token refresh fail", err)
+
+			select {
+			case <-time.After(refreshRetryInterval):
+			case <-rpcConn.DisconnectNotify():
+				return
+			case <-c.stopCtx.Done():
+				return
+			}
+		}
+	}
+}
+
+// waitRefresh blocks until the current access token is due for refresh, it
+// returns false once rpcConn is disconnected or the client is closed
+func (c *DeribitWSClient) waitRefresh(rpcConn *jsonrpc2.Conn) bool {
+	for {
+		var wait <-chan time.Time
+		var timer *time.Timer
+		if at, ok := c.refreshAt(); ok {
+			timer = time.NewTimer(time.Until(at))
+			wait = timer.C
+		}
+
+		select {
+		case <-wait:
+			return true
+		case <-c.authUpdated:
+		case <-rpcConn.DisconnectNotify():
+		case <-c.stopCtx.Done():
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-rpcConn.DisconnectNotify():
+			return false
+		case <-c.stopCtx.Done():
+			return false
+		default:
+		}
+	}
+}
+
+// refreshAt is the time the current access token is refreshed, when 80% of
+// its lifetime has passed
+func (c *DeribitWSClient) refreshAt() (time.Time, bool) {
+	auth := c.getAuthentication()
+	if auth == nil || auth.ExpiresIn <= 0 {
+		return time.Time{}, false
+	}
+	return auth.ExpiresAt.Add(-auth.ExpiresIn / 5), true
+}
+
+func (c *DeribitWSClient) accessToken() string {
+	if auth := c.getAuthentication(); auth != nil {
+		return auth.AccessToken
+	}
+	return ""
+}
+
+func (c *DeribitWSClient) hasCredential() bool {
+	return c.credential.ApiKey != "" && c.credential.SecretKey != ""
+}
+
+func isPrivateMethod(method string) bool {
+	return strings.HasPrefix(method, "private/")
+}
+
+// isUnauthorizedError reports whether the server rejected the access token
+func isUnauthorizedError(err error) bool {
+	var rpcErr *jsonrpc2.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == deribit.ErrorCodeUnauthorized
+}
@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authRecorder answers public/auth with a new token per request and records
+// the grant types
+type authRecorder struct {
+	mu        sync.Mutex
+	grants    []string
+	expiresIn int
+}
+
+func (r *authRecorder) handle(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		GrantType string `json:"grant_type"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.grants = append(r.grants, p.GrantType)
+
+	return map[string]interface{}{
+		"access_token":  fmt.Sprintf("access-token-%d", len(r.grants)),
+		"refresh_token": "refresh-token",
+		"expires_in":    r.expiresIn,
+		"scope":         fullAccessScope,
+		"token_type":    "bearer",
+	}, nil
+}
+
+func (r *authRecorder) Grants() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.grants...)
+}
+
+func withCredential(cfg *deribit.Configuration) {
+	cfg.Credential = deribit.Credential{ApiKey: "key", SecretKey: "secret"}
+}
+
+func TestTokenRefresh_RetryUnauthorized(t *testing.T) {
+	s := newMockServer(t)
+	auth := &authRecorder{expiresIn: 900}
+	s.Handle("public/auth", auth.handle)
+
+	var rejected atomic.Bool
+	s.Handle("private/get_positions", func(json.RawMessage) (interface{}, error) {
+		if !rejected.Swap(true) {
+			return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeUnauthorized, Message: "unauthorized"}
+		}
+		return []interface{}{}, nil
+	})
+
+	client := newMockClient(t, s, withCredential)
+
+	_, err := client.GetPositionsContext(context.Background(), &models.GetPositionsParams{Currency: "BTC"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, s.Calls("private/get_positions"))
+	assert.Equal(t, []string{"client_credentials", "refresh_token"}, auth.Grants())
+	assert.Equal(t, "access-token-2", client.accessToken())
+}
+
+func TestTokenRefresh_Unauthorized(t *testing.T) {
+	s := newMockServer(t)
+	s.Handle("private/get_positions", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeUnauthorized, Message: "unauthorized"}
+	})
+
+	client := newMockClient(t, s, withCredential)
+
+	_, err := client.GetPositionsContext(context.Background(), &models.GetPositionsParams{Currency: "BTC"})
+	require.Error(t, err)
+	assert.True(t, isUnauthorizedError(err), err)
+	// retried once only
+	assert.Equal(t, 2, s.Calls("private/get_positions"))
+}
+
+func TestTokenRefresh_Scheduled(t *testing.T) {
+	s := newMockServer(t)
+	auth := &authRecorder{expiresIn: 1}
+	s.Handle("public/auth", auth.handle)
+
+	client := newMockClient(t, s, withCredential)
+	var rec eventRecorder
+	rec.listen(client, EventTokenRefreshed)
+
+	require.Eventually(t, func() bool {
+		return len(auth.Grants()) >= 3 && rec.has(EventTokenRefreshed)
+	}, 5*time.Second, 20*time.Millisecond)
+
+	grants := auth.Grants()
+	assert.Equal(t, "client_credentials", grants[0])
+	assert.Equal(t, "refresh_token", grants[1])
+	assert.Equal(t, "refresh_token", grants[2])
+}
+
+func TestTokenRefresh_Reconnect(t *testing.T) {
+	s := newMockServer(t)
+	auth := &authRecorder{expiresIn: 900}
+	s.Handle("public/auth", auth.handle)
+
+	client := newMockClient(t, s, fastReconnect)
+	require.NoError(t, client.Auth("key", "secret"))
+
+	s.DropConnections()
+
+	require.Eventually(t, func() bool {
+		return client.IsConnected() && client.accessToken() == "access-token-2"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, []string{"client_credentials", "refresh_token"}, auth.Grants())
+}
+
+func TestTokenRefresh_ReconnectRefreshRejected(t *testing.T) {
+	s := newMockServer(t)
+	client := newMockClient(t, s, fastReconnect)
+	require.NoError(t, client.Auth("key", "secret"))
+
+	s.Handle("public/auth", func(json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc2.Error{Code: deribit.ErrorCodeUnauthorized, Message: "invalid_token"}
+	})
+	s.DropConnections()
+
+	require.Eventually(t, func() bool {
+		return s.Accepted() == 2 && client.IsConnected() && !client.isAuthenticated()
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -96,7 +96,7 @@ func main() {
 	//client.On("user.trades.BTC-PERPETUAL.raw", func(e *models.UserTradesNotification) {})
 	//client.On("user.trades.future.BTC.100ms", func(e *models.UserTradesNotification) {})
 
-	client.Subscribe([]string{
+	if _, err := client.Subscribe([]string{
 		//"announcements",
 		//"book.BTC-PERPETUAL.none.10.100ms",	// none/1,2,5,10,25,100,250
 		//"book.BTC-PERPETUAL.100ms",	// type: snapshot/change
@@ -116,7 +116,10 @@ func main() {
 		//"user.portfolio.btc",
 		//"user.trades.BTC-PERPETUAL.raw",
 		//"user.trades.future.BTC.100ms",
-	})
+	}); err != nil {
+		log.Printf("%v", err)
+		return
+	}
 
 	forever := make(chan bool)
 	<-forever
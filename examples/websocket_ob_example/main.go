@@ -1,11 +1,12 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+
 	"github.com/BestNathan/deribit-api/clients/websocket"
 	"github.com/BestNathan/deribit-api/pkg/deribit"
-	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/BestNathan/deribit-api/pkg/orderbook"
 )
 
 func main() {
@@ -21,25 +22,25 @@ func main() {
 		return
 	}
 
-	//client.On("book.BTC-PERPETUAL.raw", func(e *models.OrderBookRawNotification) {
-	//	jsonData, _ := json.Marshal(e)
-	//	fmt.Printf("%s\n", string(jsonData))
-	//})
-
-	client.On("book.BTC-PERPETUAL.raw", func(e *models.OrderBookRawNotification) {
-		jsonData, err := json.MarshalIndent(e, "", "    ")
-		if err != nil {
-			fmt.Printf("Error marshaling JSON: %v\n", err)
-			return
-		}
-
-		fmt.Printf("%s\n", string(jsonData))
-	})
+	book, err := websocket.WatchOrderBook(context.Background(), client, "book.BTC-PERPETUAL.raw", websocket.OrderBookOptions{})
+	if err != nil {
+		fmt.Printf("subscribe fail: %v\n", err)
+		return
+	}
+	defer book.Close()
 
-	client.Subscribe([]string{
-		"book.BTC-PERPETUAL.raw",
+	book.OnChange(func(b *orderbook.Book, update *orderbook.Update) {
+		bid, _ := b.BestBid()
+		ask, _ := b.BestAsk()
+		fmt.Printf("%d: %s@%s / %s@%s\n", update.ChangeID, bid.Amount, bid.Price, ask.Amount, ask.Price)
 	})
 
-	forever := make(chan bool)
-	<-forever
+	for {
+		select {
+		case err := <-book.Err():
+			fmt.Printf("order book error: %v\n", err)
+		case <-book.Done():
+			return
+		}
+	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/BestNathan/deribit-api/clients/websocket"
 	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
 )
 
 func main() {
@@ -38,13 +39,15 @@ func main() {
 
 	btcdvolch := websocket.ChannelDeribitVolatilityIndex(websocket.DERIBIT_VOLATILITY_INDEX_NAME_BTC)
 
-	client.On(btcdvolch, func(data string) {
-		log.Println(data)
+	client.On(btcdvolch, func(dvol *models.DeribitVolatilityIndexNotification) {
+		log.Println(dvol.IndexName, dvol.Volatility)
 	})
 
-	client.Subscribe([]string{
+	if _, err := client.Subscribe([]string{
 		btcdvolch,
-	})
+	}); err != nil {
+		log.Fatal("Subscribe fail ", err)
+	}
 
 	forever := make(chan bool)
 	<-forever
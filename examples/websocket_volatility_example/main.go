@@ -5,6 +5,7 @@ import (
 
 	"github.com/BestNathan/deribit-api/clients/websocket"
 	"github.com/BestNathan/deribit-api/pkg/deribit"
+	"github.com/BestNathan/deribit-api/pkg/models"
 )
 
 func main() {
@@ -22,13 +23,16 @@ func main() {
 
 	btcdvolch := websocket.ChannelDeribitVolatilityIndex(websocket.DERIBIT_VOLATILITY_INDEX_NAME_BTC)
 
-	client.On(btcdvolch, func(data string) {
-		log.Println(data)
+	client.On(btcdvolch, func(dvol *models.DeribitVolatilityIndexNotification) {
+		log.Println(dvol.IndexName, dvol.Volatility)
 	})
 
-	client.Subscribe([]string{
+	if _, err := client.Subscribe([]string{
 		btcdvolch,
-	})
+	}); err != nil {
+		log.Println("subscribe fail", err)
+		return
+	}
 
 	forever := make(chan bool)
 	<-forever
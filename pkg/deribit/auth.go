@@ -0,0 +1,81 @@
+package deribit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+)
+
+// AuthMethod is the public/auth grant used for a Credential
+type AuthMethod string
+
+const (
+	// AuthMethodClientCredentials sends the secret key to the server, it is
+	// used when no AuthMethod is set
+	AuthMethodClientCredentials AuthMethod = "client_credentials"
+	// AuthMethodClientSignature sends a HMAC-SHA256 signature of a timestamp
+	// and a nonce, the secret key never leaves the client
+	AuthMethodClientSignature AuthMethod = "client_signature"
+)
+
+var ErrUnknownAuthMethod = errors.New("unknown auth method")
+
+// AuthParams returns the public/auth params for the AuthMethod of c, a
+// client_signature grant is signed at the current time with a fresh nonce
+func (c Credential) AuthParams() (params interface{}, err error) {
+	switch c.AuthMethod {
+	case "", AuthMethodClientCredentials:
+		return models.ClientCredentialsParams{
+			GrantType:    string(AuthMethodClientCredentials),
+			ClientID:     c.ApiKey,
+			ClientSecret: c.SecretKey,
+			Scope:        c.Scope,
+		}, nil
+	case AuthMethodClientSignature:
+		nonce, err := NewNonce()
+		if err != nil {
+			return nil, err
+		}
+		params := NewClientSignatureParams(c.ApiKey, c.SecretKey, time.Now().UnixMilli(), nonce, "")
+		params.Scope = c.Scope
+		return params, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAuthMethod, c.AuthMethod)
+	}
+}
+
+// NewClientSignatureParams signs timestamp (in milliseconds), nonce and data
+// with secretKey
+func NewClientSignatureParams(clientID, secretKey string, timestamp int64, nonce, data string) models.ClientSignatureParams {
+	return models.ClientSignatureParams{
+		GrantType: string(AuthMethodClientSignature),
+		ClientID:  clientID,
+		Timestamp: timestamp,
+		Signature: ClientSignature(secretKey, timestamp, nonce, data),
+		Nonce:     nonce,
+		Data:      data,
+	}
+}
+
+// ClientSignature is the hex encoded HMAC-SHA256 of
+// timestamp + "\n" + nonce + "\n" + data
+func ClientSignature(secretKey string, timestamp int64, nonce, data string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	fmt.Fprintf(mac, "%d\n%s\n%s", timestamp, nonce, data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce returns a random nonce for a client_signature grant
+func NewNonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,62 @@
+package deribit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSignature(t *testing.T) {
+	tests := []struct {
+		name  string
+		nonce string
+		data  string
+		want  string
+	}{
+		{"empty data", "1iqt2wls", "", "6267de5bf689cec570fda5a65ad14dd8b96aade832e84e454365130351c0a518"},
+		{"with data", "abc", "some data", "abed830ab43667333d1dc0c4e408d1871edd742b2a1459380e9ff6fd12af5d67"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClientSignature("secret", 1576074319000, tt.nonce, tt.data))
+		})
+	}
+}
+
+func TestCredential_AuthParams(t *testing.T) {
+	params, err := Credential{ApiKey: "key", SecretKey: "secret"}.AuthParams()
+	require.NoError(t, err)
+	assert.Equal(t, models.ClientCredentialsParams{
+		GrantType:    "client_credentials",
+		ClientID:     "key",
+		ClientSecret: "secret",
+	}, params)
+
+	before := time.Now().UnixMilli()
+	params, err = Credential{ApiKey: "key", SecretKey: "secret", AuthMethod: AuthMethodClientSignature}.AuthParams()
+	require.NoError(t, err)
+
+	p, ok := params.(models.ClientSignatureParams)
+	require.True(t, ok)
+	assert.Equal(t, "client_signature", p.GrantType)
+	assert.Equal(t, "key", p.ClientID)
+	assert.GreaterOrEqual(t, p.Timestamp, before)
+	assert.NotEmpty(t, p.Nonce)
+	assert.Equal(t, ClientSignature("secret", p.Timestamp, p.Nonce, ""), p.Signature)
+
+	_, err = Credential{AuthMethod: "password"}.AuthParams()
+	assert.True(t, errors.Is(err, ErrUnknownAuthMethod), err)
+}
+
+func TestNewNonce(t *testing.T) {
+	a, err := NewNonce()
+	require.NoError(t, err)
+	b, err := NewNonce()
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
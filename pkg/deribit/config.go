@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/BestNathan/deribit-api/pkg/frame"
+	"github.com/BestNathan/deribit-api/pkg/models"
 	"github.com/sirupsen/logrus"
 )
 
@@ -31,18 +33,44 @@ func getEnvWithDefault(key, defaultValue string) string {
 type Credential struct {
 	ApiKey    string
 	SecretKey string
+	// AuthMethod selects the public/auth grant, AuthMethodClientCredentials
+	// when empty
+	AuthMethod AuthMethod
+	// Scope is requested on authentication, the server grants the maximum
+	// scope of the key when empty
+	Scope models.Scopes
 }
 
 type WebsocketConfiguration struct {
-	Url                  string
-	AutoReconnect        bool
+	Url           string
+	AutoReconnect bool
+	// ReconnectPolicy controls dial retries, DefaultReconnectPolicy is used
+	// when nil, starting at ReconnectDuration if set
+	ReconnectPolicy      ReconnectPolicy
 	ReconnectDuration    time.Duration
 	AutoStart            bool
 	ReadLimit            int64
 	DialWebsocketTimeout time.Duration
 	CallTimeout          time.Duration
-	TestDuration         time.Duration
-	HeartBeatInterval    float64
+	// Deprecated: the client answers the server's heartbeat test_request
+	// messages instead of polling public/test, TestDuration is ignored.
+	TestDuration      time.Duration
+	HeartBeatInterval float64
+	// MaxMissedHeartbeats is how many heartbeat intervals may pass without a
+	// heartbeat before the connection is considered dead, 2 by default
+	MaxMissedHeartbeats int
+	// SubscribeChunkSize is the maximum number of channels of one subscribe
+	// or unsubscribe request, 100 by default
+	SubscribeChunkSize int
+	// SubscribeRateLimit paces subscribe and unsubscribe requests,
+	// DefaultSubscribeRateLimit when nil
+	SubscribeRateLimit *RateLimit
+	// SubscribeRetryPolicy retries subscribe and unsubscribe requests rejected
+	// with ErrorCodeTooManyRequests, DefaultSubscribeRetryPolicy when nil
+	SubscribeRetryPolicy ReconnectPolicy
+	// FrameInterceptors see every frame sent and received, the first being
+	// the outermost
+	FrameInterceptors []frame.Interceptor
 }
 
 type HttpConfiguration struct {
@@ -85,14 +113,15 @@ func GetConfig() *Configuration {
 
 	return &Configuration{
 		Credential: Credential{
-			ApiKey:    getEnvWithDefault("DERIBIT_API_KEY", ""),
-			SecretKey: getEnvWithDefault("DERIBIT_API_SECRET", ""),
+			ApiKey:     getEnvWithDefault("DERIBIT_API_KEY", ""),
+			SecretKey:  getEnvWithDefault("DERIBIT_API_SECRET", ""),
+			AuthMethod: AuthMethod(getEnvWithDefault("DERIBIT_AUTH_METHOD", "")),
+			Scope:      models.ParseScopes(getEnvWithDefault("DERIBIT_AUTH_SCOPE", "")),
 		},
 		WebsocketConfiguration: &WebsocketConfiguration{
 			Url:                  wsUrl,
 			AutoReconnect:        autoReconnect,
 			AutoStart:            true,
-			TestDuration:         time.Second * 3,
 			ReconnectDuration:    time.Second,
 			CallTimeout:          time.Minute,
 			HeartBeatInterval:    30,
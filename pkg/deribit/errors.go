@@ -0,0 +1,9 @@
+package deribit
+
+// Error codes returned by the Deribit API in JSON-RPC error objects
+const (
+	// ErrorCodeTooManyRequests is returned when the rate limit is exceeded
+	ErrorCodeTooManyRequests = 10028
+	// ErrorCodeUnauthorized is returned for a wrong or expired access token
+	ErrorCodeUnauthorized = 13009
+)
@@ -0,0 +1,93 @@
+package deribit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket allowing Rate requests per second on average
+// and bursts of up to Burst requests
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultSubscribeRateLimit follows the default credits of Deribit for non
+// matching engine requests: 500 credits per request out of 50000, refilled
+// at 10000 per second
+func DefaultSubscribeRateLimit() RateLimit {
+	return RateLimit{Rate: 20, Burst: 100}
+}
+
+// DefaultSubscribeRetryPolicy retries requests rejected with
+// ErrorCodeTooManyRequests five times, backing off from 100ms up to 5s
+func DefaultSubscribeRetryPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     5,
+	}
+}
+
+// RateLimiter paces requests to a RateLimit, it is safe for concurrent use
+type RateLimiter struct {
+	limit RateLimit
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter starting with a full bucket. A limit
+// without Rate does not limit.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	return &RateLimiter{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly one refilled in the future, and returns
+// the wait until it is available
+func (l *RateLimiter) reserve() time.Duration {
+	if l.limit.Rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limit.Rate
+	if l.tokens > float64(l.limit.Burst) {
+		l.tokens = float64(l.limit.Burst)
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,47 @@
+package deribit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	l := NewRateLimiter(RateLimit{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, l.reserve(), "request %d", i)
+	}
+
+	delay := l.reserve()
+	assert.InDelta(t, time.Second, delay, float64(50*time.Millisecond))
+	// a second waiting request queues behind the first
+	assert.InDelta(t, 2*time.Second, l.reserve(), float64(50*time.Millisecond))
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l := NewRateLimiter(RateLimit{Rate: 100, Burst: 1})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Wait(ctx))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	l = NewRateLimiter(RateLimit{Rate: 0.001, Burst: 1})
+	require.NoError(t, l.Wait(cancelled))
+	assert.ErrorIs(t, l.Wait(cancelled), context.Canceled)
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	l := NewRateLimiter(RateLimit{})
+	for i := 0; i < 1000; i++ {
+		assert.Zero(t, l.reserve())
+	}
+}
@@ -0,0 +1,85 @@
+package deribit
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectPolicy decides how the websocket client retries dialing, both on
+// start and after a lost connection
+type ReconnectPolicy interface {
+	// NextDelay returns how long to wait before the given retry (starting at
+	// 1), and false once no more retries should be made
+	NextDelay(retry int) (time.Duration, bool)
+	// GiveUp is called once NextDelay returned false, with the number of
+	// retries made and the last dial error
+	GiveUp(retries int, err error)
+}
+
+// ExponentialBackoff is a ReconnectPolicy that multiplies the delay after
+// every retry, up to MaxInterval
+type ExponentialBackoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// Multiplier defaults to 2
+	Multiplier float64
+	// Jitter randomizes each delay by up to +/- Jitter of its value, in [0, 1]
+	Jitter float64
+	// MaxAttempts limits the number of retries, 0 means no limit
+	MaxAttempts int
+	// OnGiveUp is called when MaxAttempts is exhausted
+	OnGiveUp func(retries int, err error)
+}
+
+// DefaultReconnectPolicy backs off from one second up to 30 seconds with
+// 20% jitter, retrying up to MaxTryTimes times
+func DefaultReconnectPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     MaxTryTimes,
+	}
+}
+
+// NextDelay implements ReconnectPolicy
+func (b *ExponentialBackoff) NextDelay(retry int) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && retry > b.MaxAttempts {
+		return 0, false
+	}
+
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := float64(b.InitialInterval) * math.Pow(multiplier, float64(retry-1))
+	if b.MaxInterval > 0 && delay > float64(b.MaxInterval) {
+		delay = float64(b.MaxInterval)
+	}
+	// a delay growing without MaxInterval overflows a Duration, the
+	// conversion of an out of range float is undefined
+	delay = math.Min(delay, maxDelay)
+
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+
+	if delay <= 0 || math.IsNaN(delay) {
+		return 0, true
+	}
+	return time.Duration(math.Min(delay, maxDelay)), true
+}
+
+// maxDelay is the largest float64 below the maximum Duration
+var maxDelay = math.Nextafter(float64(math.MaxInt64), 0)
+
+// GiveUp implements ReconnectPolicy
+func (b *ExponentialBackoff) GiveUp(retries int, err error) {
+	if b.OnGiveUp != nil {
+		b.OnGiveUp(retries, err)
+	}
+}
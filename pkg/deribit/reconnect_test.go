@@ -0,0 +1,94 @@
+package deribit
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff_NextDelay(t *testing.T) {
+	b := &ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+
+	for _, tt := range tests {
+		delay, ok := b.NextDelay(tt.retry)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, delay, "retry %d", tt.retry)
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	b := &ExponentialBackoff{
+		InitialInterval: time.Second,
+		Jitter:          0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		delay, ok := b.NextDelay(1)
+		assert.True(t, ok)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+	}
+}
+
+func TestExponentialBackoff_MaxAttempts(t *testing.T) {
+	var gotRetries int
+	var gotErr error
+	b := &ExponentialBackoff{
+		InitialInterval: time.Millisecond,
+		MaxAttempts:     3,
+		OnGiveUp: func(retries int, err error) {
+			gotRetries = retries
+			gotErr = err
+		},
+	}
+
+	for retry := 1; retry <= 3; retry++ {
+		_, ok := b.NextDelay(retry)
+		assert.True(t, ok)
+	}
+
+	_, ok := b.NextDelay(4)
+	assert.False(t, ok)
+
+	dialErr := errors.New("dial fail")
+	b.GiveUp(3, dialErr)
+	assert.Equal(t, 3, gotRetries)
+	assert.Equal(t, dialErr, gotErr)
+}
+
+func TestExponentialBackoff_Unbounded(t *testing.T) {
+	b := &ExponentialBackoff{InitialInterval: time.Second}
+
+	for _, retry := range []int{64, 1000, 1 << 20} {
+		delay, ok := b.NextDelay(retry)
+		assert.True(t, ok)
+		assert.Greater(t, delay, time.Duration(math.MaxInt64/2), retry)
+	}
+
+	b.Jitter = 0.5
+	delay, _ := b.NextDelay(1000)
+	assert.Greater(t, delay, time.Duration(math.MaxInt64/4))
+
+	zero := &ExponentialBackoff{}
+	delay, _ = zero.NextDelay(1 << 20)
+	assert.Zero(t, delay)
+}
@@ -0,0 +1,66 @@
+package deribit
+
+import "github.com/BestNathan/deribit-api/pkg/models"
+
+// methodScopes is the scope required by each private method, methods not
+// listed only need an authenticated connection
+var methodScopes = map[string]models.Scope{
+	"private/get_account_summary":                  models.ScopeAccountRead,
+	"private/get_email_language":                   models.ScopeAccountRead,
+	"private/get_new_announcements":                models.ScopeAccountRead,
+	"private/get_subaccounts":                      models.ScopeAccountRead,
+	"private/get_subaccounts_details":              models.ScopeAccountRead,
+	"private/change_subaccount_name":               models.ScopeAccountReadWrite,
+	"private/create_subaccount":                    models.ScopeAccountReadWrite,
+	"private/disable_cancel_on_disconnect":         models.ScopeAccountReadWrite,
+	"private/disable_tfa_for_subaccount":           models.ScopeAccountReadWrite,
+	"private/enable_cancel_on_disconnect":          models.ScopeAccountReadWrite,
+	"private/set_announcement_as_read":             models.ScopeAccountReadWrite,
+	"private/set_email_for_subaccount":             models.ScopeAccountReadWrite,
+	"private/set_email_language":                   models.ScopeAccountReadWrite,
+	"private/set_password_for_subaccount":          models.ScopeAccountReadWrite,
+	"private/toggle_notifications_from_subaccount": models.ScopeAccountReadWrite,
+	"private/toggle_subaccount_login":              models.ScopeAccountReadWrite,
+
+	"private/get_margins":                            models.ScopeTradeRead,
+	"private/get_open_orders_by_currency":            models.ScopeTradeRead,
+	"private/get_open_orders_by_instrument":          models.ScopeTradeRead,
+	"private/get_order_history_by_currency":          models.ScopeTradeRead,
+	"private/get_order_history_by_instrument":        models.ScopeTradeRead,
+	"private/get_order_margin_by_ids":                models.ScopeTradeRead,
+	"private/get_order_state":                        models.ScopeTradeRead,
+	"private/get_position":                           models.ScopeTradeRead,
+	"private/get_positions":                          models.ScopeTradeRead,
+	"private/get_settlement_history_by_currency":     models.ScopeTradeRead,
+	"private/get_settlement_history_by_instrument":   models.ScopeTradeRead,
+	"private/get_stop_order_history":                 models.ScopeTradeRead,
+	"private/get_user_trades_by_currency":            models.ScopeTradeRead,
+	"private/get_user_trades_by_currency_and_time":   models.ScopeTradeRead,
+	"private/get_user_trades_by_instrument":          models.ScopeTradeRead,
+	"private/get_user_trades_by_instrument_and_time": models.ScopeTradeRead,
+	"private/get_user_trades_by_order":               models.ScopeTradeRead,
+	"private/buy":                                    models.ScopeTradeReadWrite,
+	"private/sell":                                   models.ScopeTradeReadWrite,
+	"private/edit":                                   models.ScopeTradeReadWrite,
+	"private/cancel":                                 models.ScopeTradeReadWrite,
+	"private/cancel_all":                             models.ScopeTradeReadWrite,
+	"private/cancel_all_by_currency":                 models.ScopeTradeReadWrite,
+	"private/cancel_all_by_instrument":               models.ScopeTradeReadWrite,
+	"private/cancel_by_label":                        models.ScopeTradeReadWrite,
+	"private/close_position":                         models.ScopeTradeReadWrite,
+
+	"private/get_current_deposit_address": models.ScopeWalletRead,
+	"private/get_deposits":                models.ScopeWalletRead,
+	"private/get_transfers":               models.ScopeWalletRead,
+	"private/get_withdrawals":             models.ScopeWalletRead,
+	"private/cancel_transfer_by_id":       models.ScopeWalletReadWrite,
+	"private/cancel_withdrawal":           models.ScopeWalletReadWrite,
+	"private/create_deposit_address":      models.ScopeWalletReadWrite,
+	"private/withdraw":                    models.ScopeWalletReadWrite,
+}
+
+// MethodScope returns the scope a token needs to call method
+func MethodScope(method string) (scope models.Scope, ok bool) {
+	scope, ok = methodScopes[method]
+	return
+}
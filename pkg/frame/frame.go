@@ -0,0 +1,59 @@
+package frame
+
+import "time"
+
+// Direction tells whether a frame was sent or received
+type Direction int
+
+const (
+	Outbound Direction = iota
+	Inbound
+)
+
+func (d Direction) String() string {
+	if d == Inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// ID correlates a JSON-RPC request and its response, it is a number unless
+// IsString is set
+type ID struct {
+	Num      uint64
+	Str      string
+	IsString bool
+}
+
+// Frame is one JSON-RPC message on the websocket
+type Frame struct {
+	Direction Direction
+	// Data is the raw JSON of the message
+	Data []byte
+	// Time is when the frame was read, or when it was handed to the
+	// interceptors for writing
+	Time time.Time
+	// ID correlates requests and responses, nil for notifications
+	ID *ID
+	// Method is empty for responses
+	Method string
+}
+
+// Handler writes an outbound frame, or reads the next inbound frame into
+// frame
+type Handler func(frame *Frame) error
+
+// Interceptor wraps the reading and writing of frames. An outbound frame is
+// complete before next is called and next writes it, an inbound frame is
+// empty until next has read it. Returning without calling next drops an
+// outbound frame, an error fails the write or the read.
+type Interceptor func(next Handler) Handler
+
+// Chain returns handler wrapped by interceptors, the first being the
+// outermost
+func Chain(handler Handler, interceptors ...Interceptor) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		handler = interceptors[i](handler)
+	}
+	return handler
+}
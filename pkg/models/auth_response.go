@@ -8,3 +8,8 @@ type AuthResponse struct {
 	State        string `json:"state"`
 	TokenType    string `json:"token_type"`
 }
+
+// GrantedScopes parses the scope granted to AccessToken
+func (r AuthResponse) GrantedScopes() Scopes {
+	return ParseScopes(r.Scope)
+}
@@ -0,0 +1,25 @@
+package models
+
+type BlockTradeConfirmationTrade struct {
+	Price          float64 `json:"price"`
+	InstrumentName string  `json:"instrument_name"`
+	Direction      string  `json:"direction"`
+	Amount         float64 `json:"amount"`
+}
+
+type BlockTradeConfirmationState struct {
+	Value     string `json:"value"` // initial, accepted, rejected, expired, ...
+	Timestamp int64  `json:"timestamp"`
+}
+
+type BlockTradeConfirmationsNotification struct {
+	Nonce             string                        `json:"nonce"`
+	Timestamp         int64                         `json:"timestamp"`
+	UserID            int64                         `json:"user_id"`
+	Role              string                        `json:"role"` // maker or taker
+	AppName           string                        `json:"app_name,omitempty"`
+	ComboID           string                        `json:"combo_id,omitempty"`
+	Trades            []BlockTradeConfirmationTrade `json:"trades"`
+	State             BlockTradeConfirmationState   `json:"state"`
+	CounterpartyState *BlockTradeConfirmationState  `json:"counterparty_state,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type ChartTradesNotification struct {
+	Tick   int64   `json:"tick"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+	Cost   float64 `json:"cost"`
+}
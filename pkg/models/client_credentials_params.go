@@ -4,4 +4,5 @@ type ClientCredentialsParams struct {
 	GrantType    string `json:"grant_type"`
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
+	Scope        Scopes `json:"scope,omitempty"`
 }
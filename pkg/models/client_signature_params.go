@@ -0,0 +1,11 @@
+package models
+
+type ClientSignatureParams struct {
+	GrantType string `json:"grant_type"`
+	ClientID  string `json:"client_id"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+	Nonce     string `json:"nonce"`
+	Data      string `json:"data"`
+	Scope     Scopes `json:"scope,omitempty"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type ExchangeTokenParams struct {
+	RefreshToken string `json:"refresh_token"`
+	SubjectID    int64  `json:"subject_id"`
+	Scope        Scopes `json:"scope,omitempty"`
+}
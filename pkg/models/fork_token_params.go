@@ -0,0 +1,6 @@
+package models
+
+type ForkTokenParams struct {
+	RefreshToken string `json:"refresh_token"`
+	SessionName  string `json:"session_name"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// IncrementalTickerNotification is a full ticker when Type is "snapshot",
+// "change" notifications set only the fields that changed
+type IncrementalTickerNotification struct {
+	Type           string `json:"type"` // snapshot or change
+	Timestamp      int64  `json:"timestamp"`
+	InstrumentName string `json:"instrument_name"`
+	Stats          *struct {
+		Volume *float64 `json:"volume,omitempty"`
+		Low    *float64 `json:"low,omitempty"`
+		High   *float64 `json:"high,omitempty"`
+	} `json:"stats,omitempty"`
+	State                  *string  `json:"state,omitempty"`
+	SettlementPrice        *float64 `json:"settlement_price,omitempty"`
+	OpenInterest           *float64 `json:"open_interest,omitempty"`
+	MinPrice               *float64 `json:"min_price,omitempty"`
+	MaxPrice               *float64 `json:"max_price,omitempty"`
+	MarkPrice              *float64 `json:"mark_price,omitempty"`
+	LastPrice              *float64 `json:"last_price,omitempty"`
+	IndexPrice             *float64 `json:"index_price,omitempty"`
+	EstimatedDeliveryPrice *float64 `json:"estimated_delivery_price,omitempty"`
+	Funding8H              *float64 `json:"funding_8h,omitempty"`
+	CurrentFunding         *float64 `json:"current_funding,omitempty"`
+	BestBidPrice           *float64 `json:"best_bid_price,omitempty"`
+	BestBidAmount          *float64 `json:"best_bid_amount,omitempty"`
+	BestAskPrice           *float64 `json:"best_ask_price,omitempty"`
+	BestAskAmount          *float64 `json:"best_ask_amount,omitempty"`
+}
+
+// Apply sets the fields present in n on ticker, a snapshot followed by its
+// changes gives the current ticker
+func (n *IncrementalTickerNotification) Apply(ticker *TickerNotification) {
+	ticker.Timestamp = n.Timestamp
+	ticker.InstrumentName = n.InstrumentName
+	if n.Stats != nil {
+		setFloat(&ticker.Stats.Volume, n.Stats.Volume)
+		setFloat(&ticker.Stats.Low, n.Stats.Low)
+		setFloat(&ticker.Stats.High, n.Stats.High)
+	}
+	if n.State != nil {
+		ticker.State = *n.State
+	}
+	setFloat(&ticker.SettlementPrice, n.SettlementPrice)
+	setFloat(&ticker.OpenInterest, n.OpenInterest)
+	setFloat(&ticker.MinPrice, n.MinPrice)
+	setFloat(&ticker.MaxPrice, n.MaxPrice)
+	setFloat(&ticker.MarkPrice, n.MarkPrice)
+	setFloat(&ticker.LastPrice, n.LastPrice)
+	setFloat(&ticker.IndexPrice, n.IndexPrice)
+	setFloat(&ticker.EstimatedDeliveryPrice, n.EstimatedDeliveryPrice)
+	setFloat(&ticker.Funding8H, n.Funding8H)
+	setFloat(&ticker.CurrentFunding, n.CurrentFunding)
+	setFloat(&ticker.BestBidPrice, n.BestBidPrice)
+	setFloat(&ticker.BestBidAmount, n.BestBidAmount)
+	setFloat(&ticker.BestAskPrice, n.BestAskPrice)
+	setFloat(&ticker.BestAskAmount, n.BestAskAmount)
+}
+
+func setFloat(dst *float64, v *float64) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+// instrument states of instrument.state notifications
+const (
+	InstrumentStateCreated    = "created"
+	InstrumentStateStarted    = "started"
+	InstrumentStateSettled    = "settled"
+	InstrumentStateClosed     = "closed"
+	InstrumentStateTerminated = "terminated"
+)
+
+type InstrumentStateNotification struct {
+	Timestamp      int64  `json:"timestamp"`
+	State          string `json:"state"`
+	InstrumentName string `json:"instrument_name"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type LogoutParams struct {
+	InvalidateToken bool `json:"invalidate_token"`
+}
@@ -0,0 +1,367 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInvalidOrderBookItem is returned for a book item that is not a JSON
+	// array ["action",price,amount]
+	ErrInvalidOrderBookItem = errors.New("invalid order book item")
+	// ErrFixedPrecision is returned by ParseFixed for a number with more
+	// decimals than the scale
+	ErrFixedPrecision = errors.New("number exceeds fixed-point precision")
+)
+
+// maxExponent bounds the parsed exponents, numbers beyond it are zero or out
+// of range of the fixed-point values and parsed by decimal.NewFromString
+const maxExponent = 1 << 20
+
+// SplitOrderBookItem splits the book item ["action",price,amount] into the
+// action and the literals of price and amount, subslices of b. Any other JSON,
+// including an action with escapes, is rejected. It does not allocate.
+func SplitOrderBookItem(b []byte) (action, price, amount []byte, err error) {
+	s := itemScanner{b: b}
+	var ok bool
+	if s.next('[') {
+		if action, ok = s.str(); ok && s.next(',') {
+			if price, ok = s.number(); ok && s.next(',') {
+				if amount, ok = s.number(); ok && s.next(']') && s.end() {
+					return action, price, amount, nil
+				}
+			}
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%w: %s", ErrInvalidOrderBookItem, b)
+}
+
+// OrderBookDecimalItem is an OrderBookNotificationItem with exact decimal
+// price and amount
+type OrderBookDecimalItem struct {
+	Action string
+	Price  decimal.Decimal
+	Amount decimal.Decimal
+}
+
+// UnmarshalJSON decodes ["action",price,amount], see
+// OrderBookNotificationItem.UnmarshalJSON
+func (item *OrderBookDecimalItem) UnmarshalJSON(b []byte) error {
+	action, price, amount, err := SplitOrderBookItem(b)
+	if err != nil {
+		return err
+	}
+
+	p, err := ParseDecimal(price)
+	if err != nil {
+		return fmt.Errorf("%w: price: %w", ErrInvalidOrderBookItem, err)
+	}
+	a, err := ParseDecimal(amount)
+	if err != nil {
+		return fmt.Errorf("%w: amount: %w", ErrInvalidOrderBookItem, err)
+	}
+
+	item.Action, item.Price, item.Amount = orderBookAction(action), p, a
+	return nil
+}
+
+// OrderBookFixedItem is an order book item with price and amount as integers
+// scaled by a power of ten, see DecodeFixed
+type OrderBookFixedItem struct {
+	Action string
+	Price  int64
+	Amount int64
+}
+
+// DecodeFixed decodes the book item b with the price scaled by 10^priceScale
+// and the amount by 10^amountScale, e.g. ["new",59786.5,10.0] with scales 1
+// and 0 is {new 597865 10}. Numbers with more decimals than their scale are
+// rejected with ErrFixedPrecision.
+func (item *OrderBookFixedItem) DecodeFixed(b []byte, priceScale, amountScale int) error {
+	action, price, amount, err := SplitOrderBookItem(b)
+	if err != nil {
+		return err
+	}
+
+	p, err := ParseFixed(price, priceScale)
+	if err != nil {
+		return fmt.Errorf("%w: price: %w", ErrInvalidOrderBookItem, err)
+	}
+	a, err := ParseFixed(amount, amountScale)
+	if err != nil {
+		return fmt.Errorf("%w: amount: %w", ErrInvalidOrderBookItem, err)
+	}
+
+	item.Action, item.Price, item.Amount = orderBookAction(action), p, a
+	return nil
+}
+
+// ParseDecimal returns the JSON number b as an exact decimal.Decimal
+func ParseDecimal(b []byte) (decimal.Decimal, error) {
+	n, ok := splitNumber(b)
+	if !ok {
+		return decimal.Decimal{}, fmt.Errorf("invalid number %q", b)
+	}
+
+	if m, ok := n.mantissa(); ok && n.exp > -maxExponent && n.exp < maxExponent {
+		v := int64(m)
+		if n.neg {
+			v = -v
+		}
+		return decimal.New(v, int32(n.exp-len(n.frac))), nil
+	}
+	return decimal.NewFromString(string(b))
+}
+
+// ParseFixed returns the JSON number b scaled by 10^scale, e.g. 59786.5 with
+// scale 2 is 5978650. It fails with ErrFixedPrecision when b has more
+// decimals than scale and with strconv.ErrRange when the result overflows an
+// int64.
+func ParseFixed(b []byte, scale int) (int64, error) {
+	n, ok := splitNumber(b)
+	if !ok {
+		return 0, fmt.Errorf("invalid number %q", b)
+	}
+
+	// the digits of b times 10^shift is the result
+	shift := scale + n.exp - len(n.frac)
+	for shift < 0 && n.trimZero() {
+		shift++
+	}
+	if shift < 0 {
+		if n.zero() {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %s at scale %d", ErrFixedPrecision, b, scale)
+	}
+
+	m, ok := n.mantissa()
+	for ; ok && m != 0 && shift > 0; shift-- {
+		ok = m <= math.MaxInt64/10
+		m *= 10
+	}
+	if !ok {
+		return 0, fmt.Errorf("%s at scale %d: %w", b, scale, strconv.ErrRange)
+	}
+
+	if n.neg {
+		return -int64(m), nil
+	}
+	return int64(m), nil
+}
+
+// parseFloat parses the JSON number b, the conversion to a string does not
+// escape and is not allocated for short numbers
+func parseFloat(b []byte) (float64, error) {
+	return strconv.ParseFloat(string(b), 64)
+}
+
+// orderBookAction returns action as a string, without allocating for the
+// actions of Deribit
+func orderBookAction(action []byte) string {
+	switch string(action) {
+	case "new":
+		return "new"
+	case "change":
+		return "change"
+	case "delete":
+		return "delete"
+	}
+	return string(action)
+}
+
+// itemScanner reads the tokens of a book item
+type itemScanner struct {
+	b []byte
+	i int
+}
+
+func (s *itemScanner) space() {
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case ' ', '\t', '\n', '\r':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+// next consumes c after optional whitespace
+func (s *itemScanner) next(c byte) bool {
+	s.space()
+	if s.i < len(s.b) && s.b[s.i] == c {
+		s.i++
+		return true
+	}
+	return false
+}
+
+// end tells whether only whitespace is left
+func (s *itemScanner) end() bool {
+	s.space()
+	return s.i == len(s.b)
+}
+
+// str consumes a string without escapes and returns its content
+func (s *itemScanner) str() ([]byte, bool) {
+	if !s.next('"') {
+		return nil, false
+	}
+	for j := s.i; j < len(s.b); j++ {
+		switch c := s.b[j]; {
+		case c == '"':
+			v := s.b[s.i:j]
+			s.i = j + 1
+			return v, utf8.Valid(v)
+		case c == '\\' || c < 0x20:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// number consumes a JSON number and returns its literal
+func (s *itemScanner) number() ([]byte, bool) {
+	s.space()
+	n := numberLen(s.b[s.i:])
+	if n == 0 {
+		return nil, false
+	}
+	v := s.b[s.i : s.i+n]
+	s.i += n
+	return v, true
+}
+
+// numberLen returns the length of the JSON number at the start of b, 0 when
+// there is none
+func numberLen(b []byte) int {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(b) && b[i] == '0':
+		i++
+	case i < len(b) && b[i] >= '1' && b[i] <= '9':
+		i = skipDigits(b, i)
+	default:
+		return 0
+	}
+
+	if i < len(b) && b[i] == '.' {
+		j := skipDigits(b, i+1)
+		if j == i+1 {
+			return 0
+		}
+		i = j
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		j := skipDigits(b, i)
+		if j == i {
+			return 0
+		}
+		i = j
+	}
+	return i
+}
+
+func skipDigits(b []byte, i int) int {
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// numberParts is a JSON number -whole.frac e exp
+type numberParts struct {
+	neg   bool
+	whole []byte
+	frac  []byte
+	exp   int
+}
+
+// splitNumber splits the JSON number b, false when b is not one
+func splitNumber(b []byte) (n numberParts, ok bool) {
+	if len(b) == 0 || numberLen(b) != len(b) {
+		return n, false
+	}
+
+	i := 0
+	if b[0] == '-' {
+		n.neg, i = true, 1
+	}
+	end := skipDigits(b, i)
+	n.whole, i = b[i:end], end
+	if i < len(b) && b[i] == '.' {
+		end = skipDigits(b, i+1)
+		n.frac, i = b[i+1:end], end
+	}
+	if i < len(b) {
+		i++ // e or E
+		neg := b[i] == '-'
+		if b[i] == '+' || b[i] == '-' {
+			i++
+		}
+		for ; i < len(b); i++ {
+			if n.exp < maxExponent {
+				n.exp = n.exp*10 + int(b[i]-'0')
+			}
+		}
+		if neg {
+			n.exp = -n.exp
+		}
+	}
+	return n, true
+}
+
+// trimZero drops a trailing zero digit keeping the value, false when the
+// last digit is not zero
+func (n *numberParts) trimZero() bool {
+	switch {
+	case len(n.frac) > 0 && n.frac[len(n.frac)-1] == '0':
+		n.frac = n.frac[:len(n.frac)-1]
+	case len(n.frac) == 0 && len(n.whole) > 0 && n.whole[len(n.whole)-1] == '0':
+		n.whole = n.whole[:len(n.whole)-1]
+		n.exp++
+	default:
+		return false
+	}
+	return true
+}
+
+// zero tells whether all digits are zero
+func (n *numberParts) zero() bool {
+	for _, digits := range [][]byte{n.whole, n.frac} {
+		for _, c := range digits {
+			if c != '0' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mantissa returns the digits as an integer, false when it exceeds an int64
+func (n *numberParts) mantissa() (uint64, bool) {
+	var m uint64
+	for _, digits := range [][]byte{n.whole, n.frac} {
+		for _, c := range digits {
+			d := uint64(c - '0')
+			if m > (math.MaxInt64-d)/10 {
+				return 0, false
+			}
+			m = m*10 + d
+		}
+	}
+	return m, true
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var invalidOrderBookItems = []string{
+	``,
+	`[]`,
+	`["new",1]`,
+	`["new",1,2,3]`,
+	`["new",1,2`,
+	`["new",1,2]x`,
+	`["new",1,2],`,
+	`[new,1,2]`,
+	`["new",,2]`,
+	`["new","1",2]`,
+	`["new",1,null]`,
+	`["ne\"w",1,2]`,
+	`["new",01,2]`,
+	`["new",1.,2]`,
+	`["new",.5,2]`,
+	`["new",+1,2]`,
+	`["new",1e,2]`,
+	`["new",-,2]`,
+	`["new",NaN,2]`,
+	`["new",1,2e400]`,
+	`{"action":"new","price":1,"amount":2}`,
+}
+
+func TestSplitOrderBookItem(t *testing.T) {
+	action, price, amount, err := SplitOrderBookItem([]byte(` [ "change" , 6947.5 ,-1e-3 ] `))
+	require.NoError(t, err)
+	assert.Equal(t, "change", string(action))
+	assert.Equal(t, "6947.5", string(price))
+	assert.Equal(t, "-1e-3", string(amount))
+}
+
+func TestOrderBookNotificationItem_UnmarshalJSON(t *testing.T) {
+	var item OrderBookNotificationItem
+	require.NoError(t, json.Unmarshal([]byte(`["new",59786.5,10.0]`), &item))
+	assert.Equal(t, OrderBookNotificationItem{Action: "new", Price: 59786.5, Amount: 10}, item)
+
+	for _, data := range invalidOrderBookItems {
+		t.Run(data, func(t *testing.T) {
+			var item OrderBookNotificationItem
+			err := item.UnmarshalJSON([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidOrderBookItem)
+			assert.Equal(t, OrderBookNotificationItem{}, item)
+		})
+	}
+
+	var notification OrderBookRawNotification
+	err := jsoniter.Unmarshal([]byte(`{"change_id":1,"bids":[["new",1,2]],"asks":[["new",abc,2]]}`), &notification)
+	assert.Error(t, err)
+}
+
+func TestOrderBookDecimalItem_UnmarshalJSON(t *testing.T) {
+	var items []OrderBookDecimalItem
+	require.NoError(t, json.Unmarshal([]byte(`[["new",59786.5,10.0],["delete",0.1,0],["change",12345678901234567890.5,1e-2]]`), &items))
+	require.Len(t, items, 3)
+
+	assert.Equal(t, "new", items[0].Action)
+	assert.Equal(t, "59786.5", items[0].Price.String())
+	assert.Equal(t, "10", items[0].Amount.String())
+	assert.Equal(t, "0.1", items[1].Price.String())
+	assert.True(t, items[1].Amount.IsZero())
+	assert.Equal(t, "12345678901234567890.5", items[2].Price.String())
+	assert.Equal(t, "0.01", items[2].Amount.String())
+
+	var item OrderBookDecimalItem
+	assert.ErrorIs(t, item.UnmarshalJSON([]byte(`["new",1,x]`)), ErrInvalidOrderBookItem)
+}
+
+func TestOrderBookRawDecimalNotification(t *testing.T) {
+	var notification OrderBookRawDecimalNotification
+	require.NoError(t, jsoniter.Unmarshal([]byte(`{"change_id":2,"prev_change_id":1,"bids":[["new",59786.123456789012345,0.1]],"asks":[]}`), &notification))
+	assert.Equal(t, int64(1), notification.PrevChangeID)
+	require.Len(t, notification.Bids, 1)
+	assert.Equal(t, "59786.123456789012345", notification.Bids[0].Price.String())
+
+	err := jsoniter.Unmarshal([]byte(`{"change_id":1,"bids":[["new",1,2]],"asks":[["new",abc,2]]}`), &notification)
+	assert.Error(t, err)
+}
+
+func TestOrderBookFixedItem_DecodeFixed(t *testing.T) {
+	var item OrderBookFixedItem
+	require.NoError(t, item.DecodeFixed([]byte(`["new",59786.5,10.0]`), 1, 0))
+	assert.Equal(t, OrderBookFixedItem{Action: "new", Price: 597865, Amount: 10}, item)
+
+	err := item.DecodeFixed([]byte(`["new",59786.25,10.0]`), 1, 0)
+	assert.ErrorIs(t, err, ErrInvalidOrderBookItem)
+	assert.ErrorIs(t, err, ErrFixedPrecision)
+}
+
+func TestParseFixed(t *testing.T) {
+	tests := []struct {
+		number string
+		scale  int
+		want   int64
+		err    error
+	}{
+		{"0", 2, 0, nil},
+		{"-0.0", 2, 0, nil},
+		{"59786.5", 2, 5978650, nil},
+		{"59786.50", 1, 597865, nil},
+		{"-1.25", 2, -125, nil},
+		{"1e3", 0, 1000, nil},
+		{"1.5E-2", 3, 15, nil},
+		{"1200", -2, 12, nil},
+		{"0e-99999999", 0, 0, nil},
+		{"0.0000000000000000000000000", 0, 0, nil},
+		{"9223372036854775807", 0, math.MaxInt64, nil},
+		{"-9223372036854775807", 0, -math.MaxInt64, nil},
+		{"1.25", 1, 0, ErrFixedPrecision},
+		{"1e-9", 8, 0, ErrFixedPrecision},
+		{"1e99999999", 0, 0, strconv.ErrRange},
+		{"9223372036854775808", 0, 0, strconv.ErrRange},
+		{"92233720368547758.08", 3, 0, strconv.ErrRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.number, tt.scale), func(t *testing.T) {
+			got, err := ParseFixed([]byte(tt.number), tt.scale)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParseFixed([]byte("1.2.3"), 2)
+	assert.Error(t, err)
+}
+
+func TestParseDecimal(t *testing.T) {
+	for _, number := range []string{"0", "-0.5", "59786.5", "1e-8", "-1.5E+3", "123456789012345678901234567890.123", "1e99999999"} {
+		got, err := ParseDecimal([]byte(number))
+		require.NoError(t, err, number)
+		assert.True(t, decimal.RequireFromString(number).Equal(got), "%s: %s", number, got)
+	}
+
+	_, err := ParseDecimal([]byte("1."))
+	assert.Error(t, err)
+}
+
+func FuzzOrderBookNotificationItem(f *testing.F) {
+	f.Add(`["new",59786.5,10.0]`)
+	f.Add(` [ "delete" , -0 , 1E+2 ] `)
+	for _, data := range invalidOrderBookItems {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var item OrderBookNotificationItem
+		err := item.UnmarshalJSON([]byte(data))
+
+		// encoding/json is the reference, the strict decoder accepts the
+		// same arrays except for actions with escapes
+		var values []interface{}
+		valid := json.Unmarshal([]byte(data), &values) == nil && len(values) == 3
+		var action string
+		var price, amount float64
+		if valid {
+			var ok1, ok2, ok3 bool
+			action, ok1 = values[0].(string)
+			price, ok2 = values[1].(float64)
+			amount, ok3 = values[2].(float64)
+			valid = ok1 && ok2 && ok3 && !strings.Contains(data, `\`) && utf8.ValidString(data)
+		}
+
+		if !valid {
+			assert.ErrorIs(t, err, ErrInvalidOrderBookItem)
+			return
+		}
+		require.NoError(t, err)
+		assert.Equal(t, OrderBookNotificationItem{Action: action, Price: price, Amount: amount}, item)
+
+		var d OrderBookDecimalItem
+		if err := d.UnmarshalJSON([]byte(data)); err == nil {
+			assert.Equal(t, action, d.Action)
+		}
+	})
+}
+
+func FuzzParseFixed(f *testing.F) {
+	f.Add("59786.5", 2)
+	f.Add("-1.25e-1", 3)
+	f.Add("9223372036854775807", 0)
+	f.Add("1e-9", 8)
+
+	f.Fuzz(func(t *testing.T, number string, scale int) {
+		if scale < -30 || scale > 30 {
+			return
+		}
+		got, err := ParseFixed([]byte(number), scale)
+
+		n, ok := splitNumber([]byte(number))
+		if !ok {
+			assert.Error(t, err)
+			return
+		}
+		if n.exp < -100 || n.exp > 100 {
+			return
+		}
+		want := decimal.RequireFromString(number)
+
+		scaled := want.Shift(int32(scale))
+		switch {
+		case !scaled.Equal(scaled.Truncate(0)):
+			assert.ErrorIs(t, err, ErrFixedPrecision)
+		case scaled.Abs().GreaterThan(decimal.NewFromInt(math.MaxInt64)):
+			assert.ErrorIs(t, err, strconv.ErrRange)
+		default:
+			require.NoError(t, err)
+			assert.Equal(t, scaled.IntPart(), got)
+		}
+	})
+}
+
+// legacyUnmarshalOrderBookItem is the former OrderBookNotificationItem
+// decoder, the baseline of the benchmarks
+func legacyUnmarshalOrderBookItem(item *OrderBookNotificationItem, b []byte) error {
+	s := strings.TrimLeft(string(b), "[")
+	s = strings.TrimRight(s, "]")
+	l := strings.Split(s, ",")
+
+	if len(l) != 3 {
+		return fmt.Errorf("fail to UnmarshalJSON [%v]", string(b))
+	}
+
+	item.Action = strings.ReplaceAll(l[0], `"`, "")
+	item.Price, _ = strconv.ParseFloat(l[1], 64)
+	item.Amount, _ = strconv.ParseFloat(l[2], 64)
+
+	return nil
+}
+
+var benchmarkOrderBookItem = []byte(`["change",59786.5,82640.0]`)
+
+func BenchmarkOrderBookNotificationItem_Legacy(b *testing.B) {
+	b.ReportAllocs()
+	var item OrderBookNotificationItem
+	for i := 0; i < b.N; i++ {
+		if err := legacyUnmarshalOrderBookItem(&item, benchmarkOrderBookItem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOrderBookNotificationItem_Float(b *testing.B) {
+	b.ReportAllocs()
+	var item OrderBookNotificationItem
+	for i := 0; i < b.N; i++ {
+		if err := item.UnmarshalJSON(benchmarkOrderBookItem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOrderBookNotificationItem_Decimal(b *testing.B) {
+	b.ReportAllocs()
+	var item OrderBookDecimalItem
+	for i := 0; i < b.N; i++ {
+		if err := item.UnmarshalJSON(benchmarkOrderBookItem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOrderBookNotificationItem_Fixed(b *testing.B) {
+	b.ReportAllocs()
+	var item OrderBookFixedItem
+	for i := 0; i < b.N; i++ {
+		if err := item.DecodeFixed(benchmarkOrderBookItem, 1, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// legacyOrderBookItem decodes notifications with the former decoder
+type legacyOrderBookItem OrderBookNotificationItem
+
+func (item *legacyOrderBookItem) UnmarshalJSON(b []byte) error {
+	return legacyUnmarshalOrderBookItem((*OrderBookNotificationItem)(item), b)
+}
+
+func benchmarkRawNotification() []byte {
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = fmt.Sprintf(`["change",%d.5,%d.0]`, 59000+i, 1000*i)
+	}
+	list := strings.Join(items, ",")
+	return []byte(`{"timestamp":1554375447971,"instrument_name":"BTC-PERPETUAL","prev_change_id":1,"change_id":2,"bids":[` + list + `],"asks":[` + list + `]}`)
+}
+
+func BenchmarkOrderBookRawNotification_Legacy(b *testing.B) {
+	data := benchmarkRawNotification()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var n struct {
+			Bids []legacyOrderBookItem `json:"bids"`
+			Asks []legacyOrderBookItem `json:"asks"`
+		}
+		if err := jsoniter.Unmarshal(data, &n); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOrderBookRawNotification_Float(b *testing.B) {
+	data := benchmarkRawNotification()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var n OrderBookRawNotification
+		if err := jsoniter.Unmarshal(data, &n); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,8 +2,6 @@ package models
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/shopspring/decimal"
 )
@@ -20,26 +18,32 @@ type OrderBookGroupNotification struct {
 // ["change",6947.0,82640.0]
 // ["new",6942.5,6940.0]
 // ["delete",6914.0,0.0]
+//
+// Use OrderBookDecimalItem or OrderBookFixedItem for exact values.
 type OrderBookNotificationItem struct {
 	Action string  `json:"action"`
 	Price  float64 `json:"price"`
 	Amount float64 `json:"amount"`
 }
 
+// UnmarshalJSON decodes ["action",price,amount], rejecting any other JSON
+// with ErrInvalidOrderBookItem
 func (item *OrderBookNotificationItem) UnmarshalJSON(b []byte) error {
-	// b: ["new",59786.0,10.0]
-	s := strings.TrimLeft(string(b), "[")
-	s = strings.TrimRight(s, "]")
-	l := strings.Split(s, ",")
-
-	if len(l) != 3 {
-		return fmt.Errorf("fail to UnmarshalJSON [%v]", string(b))
+	action, price, amount, err := SplitOrderBookItem(b)
+	if err != nil {
+		return err
 	}
 
-	item.Action = strings.ReplaceAll(l[0], `"`, "")
-	item.Price, _ = strconv.ParseFloat(l[1], 64)
-	item.Amount, _ = strconv.ParseFloat(l[2], 64)
+	p, err := parseFloat(price)
+	if err != nil {
+		return fmt.Errorf("%w: price: %w", ErrInvalidOrderBookItem, err)
+	}
+	a, err := parseFloat(amount)
+	if err != nil {
+		return fmt.Errorf("%w: amount: %w", ErrInvalidOrderBookItem, err)
+	}
 
+	item.Action, item.Price, item.Amount = orderBookAction(action), p, a
 	return nil
 }
 
@@ -61,3 +65,26 @@ type OrderBookRawNotification struct {
 	Bids           []OrderBookNotificationItem `json:"bids"` // [action, price, amount]
 	Asks           []OrderBookNotificationItem `json:"asks"` // [action, price, amount]
 }
+
+// OrderBookDecimalNotification is an OrderBookNotification with exact decimal
+// prices and amounts
+type OrderBookDecimalNotification struct {
+	Type           string                 `json:"type"`
+	Timestamp      int64                  `json:"timestamp"`
+	InstrumentName string                 `json:"instrument_name"`
+	PrevChangeID   int64                  `json:"prev_change_id"`
+	ChangeID       int64                  `json:"change_id"`
+	Bids           []OrderBookDecimalItem `json:"bids"` // [action, price, amount]
+	Asks           []OrderBookDecimalItem `json:"asks"` // [action, price, amount]
+}
+
+// OrderBookRawDecimalNotification is an OrderBookRawNotification with exact
+// decimal prices and amounts
+type OrderBookRawDecimalNotification struct {
+	Timestamp      int64                  `json:"timestamp"`
+	InstrumentName string                 `json:"instrument_name"`
+	PrevChangeID   int64                  `json:"prev_change_id"`
+	ChangeID       int64                  `json:"change_id"`
+	Bids           []OrderBookDecimalItem `json:"bids"` // [action, price, amount]
+	Asks           []OrderBookDecimalItem `json:"asks"` // [action, price, amount]
+}
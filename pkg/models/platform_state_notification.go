@@ -0,0 +1,12 @@
+package models
+
+// PlatformStateNotification is sent on platform_state and
+// platform_state.public_methods_state, each message sets only some fields:
+// a price index lock change, the maintenance flag or whether unauthenticated
+// public requests are allowed
+type PlatformStateNotification struct {
+	PriceIndex                         string `json:"price_index,omitempty"`
+	Locked                             bool   `json:"locked,omitempty"`
+	Maintenance                        bool   `json:"maintenance,omitempty"`
+	AllowUnauthenticatedPublicRequests *bool  `json:"allow_unauthenticated_public_requests,omitempty"`
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Scope is one entry of the space separated scope of an access token
+type Scope string
+
+const (
+	ScopeConnection  Scope = "connection"
+	ScopeMainAccount Scope = "mainaccount"
+
+	ScopeAccountNone      Scope = "account:none"
+	ScopeAccountRead      Scope = "account:read"
+	ScopeAccountReadWrite Scope = "account:read_write"
+
+	ScopeTradeNone      Scope = "trade:none"
+	ScopeTradeRead      Scope = "trade:read"
+	ScopeTradeReadWrite Scope = "trade:read_write"
+
+	ScopeWalletNone      Scope = "wallet:none"
+	ScopeWalletRead      Scope = "wallet:read"
+	ScopeWalletReadWrite Scope = "wallet:read_write"
+
+	ScopeBlockTradeRead      Scope = "block_trade:read"
+	ScopeBlockTradeReadWrite Scope = "block_trade:read_write"
+)
+
+// Access levels of the account, trade, wallet and block_trade scopes
+const (
+	AccessNone      = "none"
+	AccessRead      = "read"
+	AccessReadWrite = "read_write"
+)
+
+var accessLevels = map[string]int{
+	AccessNone:      0,
+	AccessRead:      1,
+	AccessReadWrite: 2,
+}
+
+// SessionScope names the session of the token, the token then stays valid
+// on other connections until the session expires
+func SessionScope(name string) Scope {
+	return Scope("session:" + name)
+}
+
+// ExpiresScope sets the lifetime of a session scoped token
+func ExpiresScope(seconds int) Scope {
+	return Scope("expires:" + strconv.Itoa(seconds))
+}
+
+// Name is the part before the colon, e.g. "trade" for "trade:read"
+func (s Scope) Name() string {
+	name, _, _ := strings.Cut(string(s), ":")
+	return name
+}
+
+// Value is the part after the colon, e.g. "read" for "trade:read"
+func (s Scope) Value() string {
+	_, value, _ := strings.Cut(string(s), ":")
+	return value
+}
+
+// Allows reports whether s grants at least the access of required, e.g.
+// trade:read_write allows trade:read
+func (s Scope) Allows(required Scope) bool {
+	if s.Name() != required.Name() {
+		return false
+	}
+
+	have, ok := accessLevels[s.Value()]
+	if !ok {
+		return s == required
+	}
+	want, ok := accessLevels[required.Value()]
+	if !ok {
+		return false
+	}
+	return have >= want
+}
+
+// Scopes is the scope of an access token, encoded in JSON as one space
+// separated string
+type Scopes []Scope
+
+// ParseScopes splits the space separated scope returned by public/auth
+func ParseScopes(scope string) Scopes {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	scopes := make(Scopes, len(fields))
+	for i, field := range fields {
+		scopes[i] = Scope(field)
+	}
+	return scopes
+}
+
+func (s Scopes) String() string {
+	fields := make([]string, len(s))
+	for i, scope := range s {
+		fields[i] = string(scope)
+	}
+	return strings.Join(fields, " ")
+}
+
+// Allows reports whether any scope of s allows required
+func (s Scopes) Allows(required Scope) bool {
+	for _, scope := range s {
+		if scope.Allows(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether s contains a scope named name, e.g. "trade"
+func (s Scopes) Has(name string) bool {
+	for _, scope := range s {
+		if scope.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Session returns the name of the session scope
+func (s Scopes) Session() (string, bool) {
+	for _, scope := range s {
+		if scope.Name() == "session" {
+			return scope.Value(), true
+		}
+	}
+	return "", false
+}
+
+func (s Scopes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	var scope string
+	if err := json.Unmarshal(data, &scope); err != nil {
+		return err
+	}
+	*s = ParseScopes(scope)
+	return nil
+}
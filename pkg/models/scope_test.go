@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope_Allows(t *testing.T) {
+	tests := []struct {
+		scope    Scope
+		required Scope
+		want     bool
+	}{
+		{ScopeTradeReadWrite, ScopeTradeRead, true},
+		{ScopeTradeReadWrite, ScopeTradeReadWrite, true},
+		{ScopeTradeRead, ScopeTradeRead, true},
+		{ScopeTradeRead, ScopeTradeReadWrite, false},
+		{ScopeTradeNone, ScopeTradeRead, false},
+		{ScopeWalletReadWrite, ScopeTradeRead, false},
+		{ScopeConnection, ScopeConnection, true},
+		{SessionScope("bot"), SessionScope("bot"), true},
+		{SessionScope("bot"), SessionScope("other"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.scope.Allows(tt.required))
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes := ParseScopes("account:read trade:read_write  session:bot expires:600 mainaccount")
+	assert.Equal(t, Scopes{ScopeAccountRead, ScopeTradeReadWrite, SessionScope("bot"), ExpiresScope(600), ScopeMainAccount}, scopes)
+	assert.Equal(t, "account:read trade:read_write session:bot expires:600 mainaccount", scopes.String())
+
+	assert.True(t, scopes.Allows(ScopeTradeRead))
+	assert.False(t, scopes.Allows(ScopeWalletRead))
+	assert.True(t, scopes.Has("trade"))
+	assert.False(t, scopes.Has("wallet"))
+
+	session, ok := scopes.Session()
+	assert.True(t, ok)
+	assert.Equal(t, "bot", session)
+
+	assert.Nil(t, ParseScopes(" "))
+}
+
+func TestScopes_JSON(t *testing.T) {
+	params := ClientCredentialsParams{
+		GrantType: "client_credentials",
+		Scope:     Scopes{SessionScope("bot"), ScopeTradeRead, ScopeWalletNone},
+	}
+
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"scope":"session:bot trade:read wallet:none"`)
+
+	var decoded ClientCredentialsParams
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, params.Scope, decoded.Scope)
+
+	data, err = json.Marshal(ClientCredentialsParams{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "scope")
+}
@@ -0,0 +1,10 @@
+package models
+
+type UserAccessLogNotification struct {
+	ID        int64  `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Log       string `json:"log"` // success, failure, enabled_tfa, ...
+	IP        string `json:"ip"`
+	Country   string `json:"country"`
+	City      string `json:"city"`
+}
@@ -0,0 +1,177 @@
+package orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+var bpsFactor = decimal.NewFromInt(10000)
+
+// LevelType is a price level with the fields of Level, e.g.
+// restmodels.PriceLevel, so the analytics take the levels of REST snapshots
+// and of live books alike. Levels are ordered best first.
+type LevelType interface {
+	~struct {
+		Price  decimal.Decimal
+		Amount decimal.Decimal
+	}
+}
+
+// Fill is the outcome of taking amount from one side of a book
+type Fill struct {
+	// Amount is the filled amount, less than requested when the side is too
+	// thin, see Complete
+	Amount   decimal.Decimal
+	Complete bool
+	// Cost is the sum of price times amount over the taken levels
+	Cost decimal.Decimal
+	// AveragePrice is Cost divided by Amount
+	AveragePrice decimal.Decimal
+	// WorstPrice is the price of the last level taken
+	WorstPrice decimal.Decimal
+	// Slippage is the distance of AveragePrice from the best price, in
+	// price units and in basis points of the best price
+	Slippage    decimal.Decimal
+	SlippageBps decimal.Decimal
+}
+
+// CostToFill walks levels until amount is filled, a buy takes the asks and a
+// sell the bids. The zero Fill is returned for empty levels or a non-positive
+// amount.
+func CostToFill[L LevelType](levels []L, amount decimal.Decimal) Fill {
+	var fill Fill
+	if len(levels) == 0 || !amount.IsPositive() {
+		return fill
+	}
+
+	remaining := amount
+	for _, l := range levels {
+		level := Level(l)
+		take := decimal.Min(remaining, level.Amount)
+		fill.Amount = fill.Amount.Add(take)
+		fill.Cost = fill.Cost.Add(take.Mul(level.Price))
+		fill.WorstPrice = level.Price
+		if remaining = remaining.Sub(take); !remaining.IsPositive() {
+			fill.Complete = true
+			break
+		}
+	}
+	if fill.Amount.IsZero() {
+		return fill
+	}
+
+	best := Level(levels[0]).Price
+	fill.AveragePrice = fill.Cost.Div(fill.Amount)
+	fill.Slippage = fill.AveragePrice.Sub(best).Abs()
+	if !best.IsZero() {
+		fill.SlippageBps = fill.Slippage.Div(best).Mul(bpsFactor)
+	}
+	return fill
+}
+
+// DepthWithinTicks returns the amount of the levels priced within ticks
+// ticks of tickSize from the best level
+func DepthWithinTicks[L LevelType](levels []L, tickSize decimal.Decimal, ticks int) decimal.Decimal {
+	return depthWithin(levels, tickSize.Mul(decimal.NewFromInt(int64(ticks))))
+}
+
+// DepthWithinBps returns the amount of the levels priced within bps basis
+// points of the best level
+func DepthWithinBps[L LevelType](levels []L, bps decimal.Decimal) decimal.Decimal {
+	if len(levels) == 0 {
+		return decimal.Zero
+	}
+	return depthWithin(levels, Level(levels[0]).Price.Mul(bps).Div(bpsFactor).Abs())
+}
+
+func depthWithin[L LevelType](levels []L, distance decimal.Decimal) decimal.Decimal {
+	total := decimal.Zero
+	if len(levels) == 0 {
+		return total
+	}
+
+	best := Level(levels[0]).Price
+	for _, l := range levels {
+		level := Level(l)
+		if level.Price.Sub(best).Abs().GreaterThan(distance) {
+			break
+		}
+		total = total.Add(level.Amount)
+	}
+	return total
+}
+
+// Microprice returns the best bid and ask prices weighted by the amount on
+// the opposite side, false when a side is empty or both best amounts are zero
+func Microprice[L LevelType](bids, asks []L) (decimal.Decimal, bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return decimal.Decimal{}, false
+	}
+
+	bid, ask := Level(bids[0]), Level(asks[0])
+	total := bid.Amount.Add(ask.Amount)
+	if total.IsZero() {
+		return decimal.Decimal{}, false
+	}
+	return bid.Price.Mul(ask.Amount).Add(ask.Price.Mul(bid.Amount)).Div(total), true
+}
+
+// Imbalance returns (bid - ask) / (bid + ask) of the amounts of the best n
+// levels of each side, all levels when n <= 0. It ranges from -1, asks only,
+// to 1, bids only, and is false when both sides are empty.
+func Imbalance[L LevelType](bids, asks []L, n int) (decimal.Decimal, bool) {
+	bid, ask := totalAmount(bids, n), totalAmount(asks, n)
+	total := bid.Add(ask)
+	if total.IsZero() {
+		return decimal.Decimal{}, false
+	}
+	return bid.Sub(ask).Div(total), true
+}
+
+func totalAmount[L LevelType](levels []L, n int) decimal.Decimal {
+	if n > 0 && n < len(levels) {
+		levels = levels[:n]
+	}
+	total := decimal.Zero
+	for _, l := range levels {
+		total = total.Add(Level(l).Amount)
+	}
+	return total
+}
+
+// CostToFill walks side of the book for amount, see CostToFill
+func (b *Book) CostToFill(side Side, amount decimal.Decimal) Fill {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return CostToFill(b.levels(side), amount)
+}
+
+// DepthWithinTicks returns the depth of side within ticks ticks of tickSize
+// from its best level, see DepthWithinTicks
+func (b *Book) DepthWithinTicks(side Side, tickSize decimal.Decimal, ticks int) decimal.Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return DepthWithinTicks(b.levels(side), tickSize, ticks)
+}
+
+// DepthWithinBps returns the depth of side within bps basis points of its
+// best level, see DepthWithinBps
+func (b *Book) DepthWithinBps(side Side, bps decimal.Decimal) decimal.Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return DepthWithinBps(b.levels(side), bps)
+}
+
+// Microprice returns the microprice of the book, see Microprice
+func (b *Book) Microprice() (decimal.Decimal, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return Microprice(b.bids, b.asks)
+}
+
+// Imbalance returns the imbalance of the best n levels of the book, see
+// Imbalance
+func (b *Book) Imbalance(n int) (decimal.Decimal, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return Imbalance(b.bids, b.asks, n)
+}
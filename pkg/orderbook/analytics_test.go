@@ -0,0 +1,116 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+
+	restmodels "github.com/BestNathan/deribit-api/clients/rest/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restBook has the bids 100x1, 99.5x2, 98x4 and the asks 101x3, 101.5x1,
+// 103x10
+func restBook(t *testing.T) *restmodels.OrderBook {
+	t.Helper()
+
+	var book restmodels.OrderBook
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"timestamp": 1700000000000,
+		"bids": [[99.5, 2], [100, 1], [98, 4]],
+		"asks": [[101, 3], [103, 10], [101.5, 1]]
+	}`), &book))
+	return &book
+}
+
+func assertDecimal(t *testing.T, want string, got decimal.Decimal) {
+	t.Helper()
+	assert.True(t, d(want).Equal(got), "want %s, got %s", want, got)
+}
+
+func TestCostToFill(t *testing.T) {
+	book := restBook(t)
+
+	fill := CostToFill(book.Asks, d("5"))
+	assert.True(t, fill.Complete)
+	assertDecimal(t, "5", fill.Amount)
+	assertDecimal(t, "507.5", fill.Cost) // 3*101 + 101.5 + 103
+	assertDecimal(t, "101.5", fill.AveragePrice)
+	assertDecimal(t, "103", fill.WorstPrice)
+	assertDecimal(t, "0.5", fill.Slippage)
+	assertDecimal(t, "49.50495", fill.SlippageBps.Round(5))
+
+	fill = CostToFill(book.Bids, d("2"))
+	assertDecimal(t, "99.75", fill.AveragePrice)
+	assertDecimal(t, "0.25", fill.Slippage)
+
+	// too thin
+	fill = CostToFill(book.Bids, d("10"))
+	assert.False(t, fill.Complete)
+	assertDecimal(t, "7", fill.Amount)
+	assertDecimal(t, "98", fill.WorstPrice)
+
+	assert.Equal(t, Fill{}, CostToFill(book.Asks, decimal.Zero))
+	assert.Equal(t, Fill{}, CostToFill([]Level(nil), d("1")))
+}
+
+func TestDepthWithin(t *testing.T) {
+	book := restBook(t)
+
+	assertDecimal(t, "1", DepthWithinTicks(book.Bids, d("0.5"), 0))
+	assertDecimal(t, "3", DepthWithinTicks(book.Bids, d("0.5"), 1))
+	assertDecimal(t, "7", DepthWithinTicks(book.Bids, d("0.5"), 4))
+
+	// 50 bps of 101 is 0.505
+	assertDecimal(t, "4", DepthWithinBps(book.Asks, d("50")))
+	assertDecimal(t, "14", DepthWithinBps(book.Asks, d("200")))
+	assertDecimal(t, "0", DepthWithinBps([]Level(nil), d("200")))
+}
+
+func TestMicropriceImbalance(t *testing.T) {
+	book := restBook(t)
+
+	// (100*3 + 101*1) / 4
+	micro, ok := Microprice(book.Bids, book.Asks)
+	require.True(t, ok)
+	assertDecimal(t, "100.25", micro)
+
+	imbalance, ok := Imbalance(book.Bids, book.Asks, 1)
+	require.True(t, ok)
+	assertDecimal(t, "-0.5", imbalance)
+	imbalance, ok = Imbalance(book.Bids, book.Asks, 0)
+	require.True(t, ok)
+	assertDecimal(t, "-0.3333333333333333", imbalance) // (7 - 14) / 21
+
+	_, ok = Microprice(book.Bids, nil)
+	assert.False(t, ok)
+	_, ok = Imbalance([]Level(nil), nil, 0)
+	assert.False(t, ok)
+}
+
+func TestBook_Analytics(t *testing.T) {
+	b := snapshot(t)
+
+	fill := b.CostToFill(Ask, d("20"))
+	assert.True(t, fill.Complete)
+	assertDecimal(t, "101.25", fill.AveragePrice) // (15*101 + 5*102) / 20
+
+	assertDecimal(t, "30", b.DepthWithinTicks(Bid, d("0.5"), 2))
+	assertDecimal(t, "60", b.DepthWithinTicks(Bid, d("1"), 2))
+	// 100 bps of 101 is 1.01
+	assertDecimal(t, "20", b.DepthWithinBps(Ask, d("100")))
+	assertDecimal(t, "15", b.DepthWithinBps(Ask, d("50")))
+	assertDecimal(t, "0", New("ETH-PERPETUAL").DepthWithinBps(Bid, d("100")))
+
+	// (100*15 + 101*10) / 25
+	micro, ok := b.Microprice()
+	require.True(t, ok)
+	assertDecimal(t, "100.4", micro)
+
+	imbalance, ok := b.Imbalance(1)
+	require.True(t, ok)
+	assertDecimal(t, "-0.2", imbalance)
+
+	assert.Equal(t, Fill{}, New("ETH-PERPETUAL").CostToFill(Bid, d("1")))
+}
@@ -0,0 +1,269 @@
+package orderbook
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// Side selects the bids or the asks of a Book
+type Side int
+
+const (
+	Bid Side = iota
+	Ask
+)
+
+func (s Side) String() string {
+	if s == Ask {
+		return "ask"
+	}
+	return "bid"
+}
+
+// Level is the total amount resting at one price
+type Level struct {
+	Price  decimal.Decimal
+	Amount decimal.Decimal
+}
+
+// ChangeFunc is called after an update was applied to book
+type ChangeFunc func(book *Book, update *Update)
+
+// Book is an in-memory order book of one instrument, safe for concurrent
+// use. Bids are kept in descending and asks in ascending price order.
+type Book struct {
+	mu         sync.RWMutex
+	instrument string
+	bids       []Level
+	asks       []Level
+	changeID   int64
+	timestamp  int64
+	listeners  []ChangeFunc
+	// synced is set by a snapshot and cleared by a gap or a crossed book
+	synced bool
+	// bridging accepts a delta overlapping the seeded snapshot
+	bridging bool
+}
+
+// New returns an empty Book of instrument
+func New(instrument string) *Book {
+	return &Book{instrument: instrument}
+}
+
+// Instrument returns the instrument name of the book
+func (b *Book) Instrument() string {
+	return b.instrument
+}
+
+// OnChange registers fn to be called after every applied update, on the
+// goroutine applying it
+func (b *Book) OnChange(fn ChangeFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// Apply applies update to the book, replacing its content when the update is
+// a snapshot. An update with an unknown action is rejected as a whole.
+//
+// A delta must follow the last applied change, otherwise it is rejected with
+// a *GapError and the book stays out of sync until the next snapshot. After
+// a Seed snapshot, deltas it already contains are skipped and the first
+// delta may start before it. A book whose best bid reaches its best ask after
+// the update is out of sync as well, Apply returns ErrCrossed.
+func (b *Book) Apply(update *Update) error {
+	if err := update.validate(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if update.Snapshot {
+		b.bids, b.asks = b.bids[:0], b.asks[:0]
+		b.synced, b.bridging = true, update.Seed
+	} else {
+		if b.bridging && update.ChangeID <= b.changeID {
+			b.mu.Unlock()
+			return nil
+		}
+		if !b.follows(update) {
+			err := &GapError{Instrument: b.instrument, ChangeID: b.changeID, PrevChangeID: update.PrevChangeID}
+			b.synced = false
+			b.mu.Unlock()
+			return err
+		}
+		b.bridging = false
+	}
+
+	for _, c := range update.Bids {
+		b.bids = applyChange(b.bids, Bid, c)
+	}
+	for _, c := range update.Asks {
+		b.asks = applyChange(b.asks, Ask, c)
+	}
+	b.changeID, b.timestamp = update.ChangeID, update.Timestamp
+
+	if len(b.bids) > 0 && len(b.asks) > 0 && b.bids[0].Price.Cmp(b.asks[0].Price) >= 0 {
+		err := fmt.Errorf("%w: %s bid %s ask %s", ErrCrossed, b.instrument, b.bids[0].Price, b.asks[0].Price)
+		b.synced = false
+		b.mu.Unlock()
+		return err
+	}
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(b, update)
+	}
+	return nil
+}
+
+// follows tells whether the delta update continues the book
+func (b *Book) follows(update *Update) bool {
+	if !b.synced {
+		return false
+	}
+	if b.bridging {
+		return update.PrevChangeID <= b.changeID
+	}
+	return update.PrevChangeID == b.changeID
+}
+
+// Synced tells whether the book holds a snapshot and every delta since,
+// false before the first snapshot and after a gap or a crossed book
+func (b *Book) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.synced
+}
+
+// ChangeID returns the change ID of the last applied update
+func (b *Book) ChangeID() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.changeID
+}
+
+// Timestamp returns the timestamp in milliseconds of the last applied update
+func (b *Book) Timestamp() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.timestamp
+}
+
+// BestBid returns the highest bid, false when there are no bids
+func (b *Book) BestBid() (Level, bool) {
+	return b.best(Bid)
+}
+
+// BestAsk returns the lowest ask, false when there are no asks
+func (b *Book) BestAsk() (Level, bool) {
+	return b.best(Ask)
+}
+
+func (b *Book) best(side Side) (Level, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	levels := b.levels(side)
+	if len(levels) == 0 {
+		return Level{}, false
+	}
+	return levels[0], true
+}
+
+// Spread returns the best ask price minus the best bid price, false when a
+// side is empty
+func (b *Book) Spread() (decimal.Decimal, bool) {
+	bid, ask, ok := b.top()
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	return ask.Price.Sub(bid.Price), true
+}
+
+// Mid returns the average of the best bid and ask prices, false when a side
+// is empty
+func (b *Book) Mid() (decimal.Decimal, bool) {
+	bid, ask, ok := b.top()
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	return bid.Price.Add(ask.Price).Div(decimal.NewFromInt(2)), true
+}
+
+func (b *Book) top() (bid, ask Level, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return Level{}, Level{}, false
+	}
+	return b.bids[0], b.asks[0], true
+}
+
+// Depth returns a copy of the best n levels of side, all levels when n <= 0
+func (b *Book) Depth(side Side, n int) []Level {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	levels := b.levels(side)
+	if n > 0 && n < len(levels) {
+		levels = levels[:n]
+	}
+	return append([]Level(nil), levels...)
+}
+
+// CumulativeSize returns the total amount of the best n levels of side, of
+// all levels when n <= 0
+func (b *Book) CumulativeSize(side Side, n int) decimal.Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return totalAmount(b.levels(side), n)
+}
+
+// Len returns the number of levels of side
+func (b *Book) Len(side Side) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.levels(side))
+}
+
+func (b *Book) levels(side Side) []Level {
+	if side == Ask {
+		return b.asks
+	}
+	return b.bids
+}
+
+// applyChange sets or removes the level of c in levels sorted best first
+func applyChange(levels []Level, side Side, c Change) []Level {
+	i := search(levels, side, c.Price)
+	found := i < len(levels) && levels[i].Price.Equal(c.Price)
+
+	if c.Action == ActionDelete || c.Amount.IsZero() {
+		if found {
+			levels = append(levels[:i], levels[i+1:]...)
+		}
+		return levels
+	}
+
+	if found {
+		levels[i].Amount = c.Amount
+		return levels
+	}
+	levels = append(levels, Level{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = Level{Price: c.Price, Amount: c.Amount}
+	return levels
+}
+
+// search returns the index of price in levels, or where it would be inserted
+func search(levels []Level, side Side, price decimal.Decimal) int {
+	if side == Ask {
+		return sort.Search(len(levels), func(i int) bool { return levels[i].Price.Cmp(price) >= 0 })
+	}
+	return sort.Search(len(levels), func(i int) bool { return levels[i].Price.Cmp(price) <= 0 })
+}
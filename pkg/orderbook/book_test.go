@@ -0,0 +1,241 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// levels renders levels as price@amount, decimals being compared by value
+func levels(l ...Level) []string {
+	result := make([]string, len(l))
+	for i, level := range l {
+		result[i] = level.Price.String() + "@" + level.Amount.String()
+	}
+	return result
+}
+
+func item(action string, price, amount float64) models.OrderBookNotificationItem {
+	return models.OrderBookNotificationItem{Action: action, Price: price, Amount: amount}
+}
+
+// snapshot returns a book with bids 100/99/98 and asks 101/102
+func snapshot(t *testing.T) *Book {
+	t.Helper()
+
+	b := New("BTC-PERPETUAL")
+	require.NoError(t, b.Apply(FromNotification(&models.OrderBookNotification{
+		Type:     "snapshot",
+		ChangeID: 10,
+		Bids:     []models.OrderBookNotificationItem{item("new", 99, 20), item("new", 100, 10), item("new", 98, 30)},
+		Asks:     []models.OrderBookNotificationItem{item("new", 102, 5), item("new", 101, 15)},
+	})))
+	return b
+}
+
+func TestBook_Snapshot(t *testing.T) {
+	b := snapshot(t)
+
+	assert.Equal(t, "BTC-PERPETUAL", b.Instrument())
+	assert.Equal(t, int64(10), b.ChangeID())
+	assert.Equal(t, []string{"100@10", "99@20", "98@30"}, levels(b.Depth(Bid, 0)...))
+	assert.Equal(t, []string{"101@15", "102@5"}, levels(b.Depth(Ask, 0)...))
+
+	// a new snapshot replaces the book
+	require.NoError(t, b.Apply(&Update{Snapshot: true, ChangeID: 20, Bids: []Change{{Action: ActionNew, Price: d("90"), Amount: d("1")}}}))
+	assert.Equal(t, []string{"90@1"}, levels(b.Depth(Bid, 0)...))
+	assert.Zero(t, b.Len(Ask))
+}
+
+func TestBook_Changes(t *testing.T) {
+	b := snapshot(t)
+
+	require.NoError(t, b.Apply(FromNotification(&models.OrderBookNotification{
+		Type:         "change",
+		PrevChangeID: 10,
+		ChangeID:     11,
+		Bids:         []models.OrderBookNotificationItem{item("change", 99, 25), item("delete", 100, 0), item("new", 99.5, 1)},
+		Asks:         []models.OrderBookNotificationItem{item("new", 100.5, 2), item("change", 102, 0), item("delete", 110, 0)},
+	})))
+
+	assert.Equal(t, int64(11), b.ChangeID())
+	assert.Equal(t, []string{"99.5@1", "99@25", "98@30"}, levels(b.Depth(Bid, 0)...))
+	assert.Equal(t, []string{"100.5@2", "101@15"}, levels(b.Depth(Ask, 0)...))
+}
+
+func TestBook_Queries(t *testing.T) {
+	b := New("BTC-PERPETUAL")
+	_, ok := b.BestBid()
+	assert.False(t, ok)
+	_, ok = b.Spread()
+	assert.False(t, ok)
+
+	b = snapshot(t)
+
+	bid, ok := b.BestBid()
+	require.True(t, ok)
+	assert.Equal(t, []string{"100@10"}, levels(bid))
+	ask, ok := b.BestAsk()
+	require.True(t, ok)
+	assert.Equal(t, []string{"101@15"}, levels(ask))
+
+	spread, ok := b.Spread()
+	require.True(t, ok)
+	assert.True(t, d("1").Equal(spread))
+	mid, ok := b.Mid()
+	require.True(t, ok)
+	assert.True(t, d("100.5").Equal(mid))
+
+	assert.Equal(t, []string{"100@10", "99@20"}, levels(b.Depth(Bid, 2)...))
+	assert.True(t, d("30").Equal(b.CumulativeSize(Bid, 2)))
+	assert.True(t, d("60").Equal(b.CumulativeSize(Bid, 0)))
+	assert.True(t, d("20").Equal(b.CumulativeSize(Ask, 5)))
+}
+
+func TestBook_UnknownAction(t *testing.T) {
+	b := snapshot(t)
+
+	err := b.Apply(&Update{ChangeID: 11, Bids: []Change{
+		{Action: ActionDelete, Price: d("100")},
+		{Action: "move", Price: d("99"), Amount: d("1")},
+	}})
+	assert.ErrorIs(t, err, ErrUnknownAction)
+
+	// nothing was applied
+	assert.Equal(t, int64(10), b.ChangeID())
+	assert.Equal(t, 3, b.Len(Bid))
+}
+
+func TestBook_OnChange(t *testing.T) {
+	b := snapshot(t)
+
+	var changes []int64
+	b.OnChange(func(book *Book, update *Update) {
+		bid, _ := book.BestBid()
+		assert.True(t, d("100.5").Equal(bid.Price))
+		changes = append(changes, update.ChangeID)
+	})
+
+	require.NoError(t, b.Apply(&Update{PrevChangeID: 10, ChangeID: 11, Bids: []Change{{Action: ActionNew, Price: d("100.5"), Amount: d("1")}}}))
+	assert.Equal(t, []int64{11}, changes)
+}
+
+func TestUpdate_From(t *testing.T) {
+	raw := FromRawNotification(&models.OrderBookRawNotification{ChangeID: 5, Bids: []models.OrderBookNotificationItem{item("new", 1, 2)}})
+	assert.True(t, raw.Snapshot)
+	assert.False(t, FromRawNotification(&models.OrderBookRawNotification{PrevChangeID: 5, ChangeID: 6}).Snapshot)
+
+	group := FromGroupNotification(&models.OrderBookGroupNotification{
+		ChangeID: 7,
+		Bids:     [][]decimal.Decimal{{d("100"), d("1")}},
+		Asks:     [][]decimal.Decimal{{d("101"), d("2")}},
+	})
+	assert.True(t, group.Snapshot)
+	assert.Equal(t, []Change{{Action: ActionNew, Price: d("101"), Amount: d("2")}}, group.Asks)
+
+	rest := FromOrderBook(&models.GetOrderBookResponse{ChangeID: 8, Bids: [][]decimal.Decimal{{d("100"), d("1")}}})
+	assert.True(t, rest.Snapshot)
+	assert.Equal(t, int64(8), rest.ChangeID)
+	assert.Len(t, rest.Bids, 1)
+}
+
+func TestUpdate_ExactValues(t *testing.T) {
+	// none of the values round-trips through a float64
+	var n models.OrderBookRawDecimalNotification
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"change_id": 1,
+		"bids": [["new",59786.123456789012345,0.1],["new",59786.1234567890123,0.2]],
+		"asks": [["new",59786.123456789012346,12345678901234567890.5]]
+	}`), &n))
+
+	b := New("BTC-PERPETUAL")
+	require.NoError(t, b.Apply(FromRawDecimalNotification(&n)))
+
+	assert.Equal(t, []string{"59786.123456789012345@0.1", "59786.1234567890123@0.2"}, levels(b.Depth(Bid, 0)...))
+	assert.Equal(t, []string{"59786.123456789012346@12345678901234567890.5"}, levels(b.Depth(Ask, 0)...))
+	assert.Equal(t, "0.3", b.CumulativeSize(Bid, 0).String())
+
+	spread, ok := b.Spread()
+	require.True(t, ok)
+	assert.Equal(t, "0.000000000000001", spread.String())
+
+	var delta models.OrderBookDecimalNotification
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "change",
+		"prev_change_id": 1,
+		"change_id": 2,
+		"bids": [["change",59786.123456789012345,0.3]],
+		"asks": []
+	}`), &delta))
+	update := FromDecimalNotification(&delta)
+	assert.False(t, update.Snapshot)
+	require.NoError(t, b.Apply(update))
+	assert.Equal(t, "0.5", b.CumulativeSize(Bid, 0).String())
+}
+
+func TestBook_Gap(t *testing.T) {
+	b := New("BTC-PERPETUAL")
+
+	// deltas before the first snapshot
+	var gap *GapError
+	require.ErrorAs(t, b.Apply(&Update{PrevChangeID: 1, ChangeID: 2}), &gap)
+	assert.False(t, b.Synced())
+
+	b = snapshot(t)
+	assert.True(t, b.Synced())
+
+	err := b.Apply(&Update{PrevChangeID: 11, ChangeID: 12, Bids: []Change{{Action: ActionDelete, Price: d("100")}}})
+	assert.ErrorIs(t, err, ErrGap)
+	require.ErrorAs(t, err, &gap)
+	assert.Equal(t, GapError{Instrument: "BTC-PERPETUAL", ChangeID: 10, PrevChangeID: 11}, *gap)
+	assert.False(t, b.Synced())
+	assert.Equal(t, 3, b.Len(Bid))
+
+	// out of sync until the next snapshot, even for a following delta
+	assert.ErrorIs(t, b.Apply(&Update{PrevChangeID: 10, ChangeID: 11}), ErrGap)
+	require.NoError(t, b.Apply(&Update{Snapshot: true, ChangeID: 20}))
+	require.NoError(t, b.Apply(&Update{PrevChangeID: 20, ChangeID: 21}))
+	assert.True(t, b.Synced())
+}
+
+func TestBook_Seed(t *testing.T) {
+	b := New("BTC-PERPETUAL")
+	require.NoError(t, b.Apply(FromOrderBook(&models.GetOrderBookResponse{
+		ChangeID: 10,
+		Bids:     [][]decimal.Decimal{{d("100"), d("1")}},
+		Asks:     [][]decimal.Decimal{{d("101"), d("1")}},
+	})))
+
+	// contained in the snapshot
+	require.NoError(t, b.Apply(&Update{PrevChangeID: 8, ChangeID: 9, Bids: []Change{{Action: ActionDelete, Price: d("100")}}}))
+	assert.Equal(t, 1, b.Len(Bid))
+	assert.Equal(t, int64(10), b.ChangeID())
+
+	// overlapping the snapshot
+	require.NoError(t, b.Apply(&Update{PrevChangeID: 9, ChangeID: 11, Bids: []Change{{Action: ActionChange, Price: d("100"), Amount: d("2")}}}))
+	assert.Equal(t, []string{"100@2"}, levels(b.Depth(Bid, 0)...))
+
+	// strict again
+	assert.ErrorIs(t, b.Apply(&Update{PrevChangeID: 10, ChangeID: 12}), ErrGap)
+}
+
+func TestBook_Crossed(t *testing.T) {
+	b := snapshot(t)
+
+	var changes int
+	b.OnChange(func(*Book, *Update) { changes++ })
+
+	err := b.Apply(&Update{PrevChangeID: 10, ChangeID: 11, Bids: []Change{{Action: ActionNew, Price: d("101"), Amount: d("1")}}})
+	assert.ErrorIs(t, err, ErrCrossed)
+	assert.False(t, b.Synced())
+	assert.Zero(t, changes)
+	assert.ErrorIs(t, b.Apply(&Update{PrevChangeID: 11, ChangeID: 12}), ErrGap)
+}
@@ -0,0 +1,190 @@
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BestNathan/deribit-api/pkg/models"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnknownAction is returned by Apply for a change that is neither
+	// new, change nor delete
+	ErrUnknownAction = errors.New("orderbook unknown action")
+	// ErrGap is wrapped by a GapError
+	ErrGap = errors.New("orderbook change gap")
+	// ErrCrossed is returned by Apply when the best bid reaches the best ask
+	ErrCrossed = errors.New("orderbook crossed")
+)
+
+// GapError is returned by Apply for a delta that does not follow the last
+// applied change
+type GapError struct {
+	Instrument string
+	// ChangeID is the last applied change
+	ChangeID int64
+	// PrevChangeID is the previous change of the rejected delta
+	PrevChangeID int64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("%s: %s at change %d, delta follows %d", ErrGap, e.Instrument, e.ChangeID, e.PrevChangeID)
+}
+
+func (e *GapError) Unwrap() error {
+	return ErrGap
+}
+
+// Actions of the items of book notifications
+const (
+	ActionNew    = "new"
+	ActionChange = "change"
+	ActionDelete = "delete"
+)
+
+// Change sets the amount at one price level, a delete or a zero amount
+// removes the level
+type Change struct {
+	Action string
+	Price  decimal.Decimal
+	Amount decimal.Decimal
+}
+
+// Update is one book notification
+type Update struct {
+	// Snapshot replaces the whole book
+	Snapshot bool
+	// Seed marks a snapshot taken apart from the notifications, deltas up to
+	// its ChangeID are skipped and the first delta may start before it
+	Seed         bool
+	Timestamp    int64
+	PrevChangeID int64
+	ChangeID     int64
+	Bids         []Change
+	Asks         []Change
+}
+
+// FromNotification returns the update of a book.{instrument}.{interval}
+// notification
+func FromNotification(n *models.OrderBookNotification) *Update {
+	return &Update{
+		Snapshot:     n.Type == "snapshot",
+		Timestamp:    n.Timestamp,
+		PrevChangeID: n.PrevChangeID,
+		ChangeID:     n.ChangeID,
+		Bids:         changes(n.Bids),
+		Asks:         changes(n.Asks),
+	}
+}
+
+// FromRawNotification returns the update of a book.{instrument}.raw
+// notification, the first notification of the channel has no previous change
+// ID and is a snapshot
+func FromRawNotification(n *models.OrderBookRawNotification) *Update {
+	return &Update{
+		Snapshot:     n.PrevChangeID == 0,
+		Timestamp:    n.Timestamp,
+		PrevChangeID: n.PrevChangeID,
+		ChangeID:     n.ChangeID,
+		Bids:         changes(n.Bids),
+		Asks:         changes(n.Asks),
+	}
+}
+
+// FromDecimalNotification is FromNotification for a notification decoded with
+// exact decimals
+func FromDecimalNotification(n *models.OrderBookDecimalNotification) *Update {
+	return &Update{
+		Snapshot:     n.Type == "snapshot",
+		Timestamp:    n.Timestamp,
+		PrevChangeID: n.PrevChangeID,
+		ChangeID:     n.ChangeID,
+		Bids:         decimalChanges(n.Bids),
+		Asks:         decimalChanges(n.Asks),
+	}
+}
+
+// FromRawDecimalNotification is FromRawNotification for a notification
+// decoded with exact decimals
+func FromRawDecimalNotification(n *models.OrderBookRawDecimalNotification) *Update {
+	return &Update{
+		Snapshot:     n.PrevChangeID == 0,
+		Timestamp:    n.Timestamp,
+		PrevChangeID: n.PrevChangeID,
+		ChangeID:     n.ChangeID,
+		Bids:         decimalChanges(n.Bids),
+		Asks:         decimalChanges(n.Asks),
+	}
+}
+
+// FromGroupNotification returns the update of a grouped book notification,
+// which always holds the whole book
+func FromGroupNotification(n *models.OrderBookGroupNotification) *Update {
+	return &Update{
+		Snapshot:  true,
+		Timestamp: n.Timestamp,
+		ChangeID:  n.ChangeID,
+		Bids:      levelChanges(n.Bids),
+		Asks:      levelChanges(n.Asks),
+	}
+}
+
+// FromOrderBook returns a Seed snapshot of a public/get_order_book response
+func FromOrderBook(r *models.GetOrderBookResponse) *Update {
+	return &Update{
+		Snapshot:  true,
+		Seed:      true,
+		Timestamp: r.Timestamp,
+		ChangeID:  int64(r.ChangeID),
+		Bids:      levelChanges(r.Bids),
+		Asks:      levelChanges(r.Asks),
+	}
+}
+
+// validate rejects unknown actions before anything is applied
+func (u *Update) validate() error {
+	for _, changes := range [][]Change{u.Bids, u.Asks} {
+		for _, c := range changes {
+			switch c.Action {
+			case ActionNew, ActionChange, ActionDelete:
+			default:
+				return fmt.Errorf("%w: %q at %s", ErrUnknownAction, c.Action, c.Price)
+			}
+		}
+	}
+	return nil
+}
+
+func changes(items []models.OrderBookNotificationItem) []Change {
+	result := make([]Change, len(items))
+	for i, item := range items {
+		result[i] = Change{
+			Action: item.Action,
+			Price:  decimal.NewFromFloat(item.Price),
+			Amount: decimal.NewFromFloat(item.Amount),
+		}
+	}
+	return result
+}
+
+// decimalChanges converts exact [action, price, amount] items
+func decimalChanges(items []models.OrderBookDecimalItem) []Change {
+	result := make([]Change, len(items))
+	for i, item := range items {
+		result[i] = Change{Action: item.Action, Price: item.Price, Amount: item.Amount}
+	}
+	return result
+}
+
+// levelChanges converts [price, amount] levels
+func levelChanges(levels [][]decimal.Decimal) []Change {
+	result := make([]Change, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			continue
+		}
+		result = append(result, Change{Action: ActionNew, Price: level[0], Amount: level[1]})
+	}
+	return result
+}